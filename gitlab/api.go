@@ -0,0 +1,332 @@
+package gitlab
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+// This file holds calls to GitLab API endpoints which the vendored go-gitlab
+// does not support yet.
+
+// transferProjectOptions represents the available transferProject() options.
+type transferProjectOptions struct {
+	Namespace interface{} `url:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// transferProject moves project to another namespace, given by ID or full
+// path.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#transfer-a-project-to-a-new-namespace
+func transferProject(client *gitlab.Client, project string, opt *transferProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s/transfer", url.QueryEscape(project))
+
+	req, err := client.NewRequest("PUT", u, opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(gitlab.Project)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
+
+// transferGroupOptions represents the available transferGroup() options.
+type transferGroupOptions struct {
+	GroupID *int `url:"group_id,omitempty" json:"group_id,omitempty"`
+}
+
+// transferGroup moves group under another parent group, or to the top level
+// when no GroupID is given.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/groups.html#transfer-a-group
+func transferGroup(client *gitlab.Client, group string, opt *transferGroupOptions, options ...gitlab.OptionFunc) (*gitlab.Group, *gitlab.Response, error) {
+	u := fmt.Sprintf("groups/%s/transfer", url.QueryEscape(group))
+
+	req, err := client.NewRequest("POST", u, opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := new(gitlab.Group)
+	resp, err := client.Do(req, g)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return g, resp, err
+}
+
+// gitlabGroup is a gitlab.Group along with the attributes the vendored
+// go-gitlab does not decode yet.
+type gitlabGroup struct {
+	gitlab.Group
+
+	MarkedForDeletionOn string `json:"marked_for_deletion_on"`
+}
+
+// getGroup gets a specific group, identified by group ID or full path.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/groups.html#details-of-a-group
+func getGroup(client *gitlab.Client, group string, options ...gitlab.OptionFunc) (*gitlabGroup, *gitlab.Response, error) {
+	u := fmt.Sprintf("groups/%s", url.QueryEscape(group))
+
+	req, err := client.NewRequest("GET", u, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := new(gitlabGroup)
+	resp, err := client.Do(req, g)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return g, resp, err
+}
+
+// gitlabProject is a gitlab.Project along with the attributes the vendored
+// go-gitlab does not decode yet.
+type gitlabProject struct {
+	gitlab.Project
+
+	Namespace *gitlabProjectNamespace `json:"namespace"`
+	Topics    []string                `json:"topics"`
+
+	ImportStatus string `json:"import_status"`
+	ImportError  string `json:"import_error"`
+
+	MarkedForDeletionAt string `json:"marked_for_deletion_at"`
+	MarkedForDeletionOn string `json:"marked_for_deletion_on"`
+
+	MergeMethod                     string `json:"merge_method"`
+	RemoveSourceBranchAfterMerge    bool   `json:"remove_source_branch_after_merge"`
+	SquashOption                    string `json:"squash_option"`
+	PrintingMergeRequestLinkEnabled bool   `json:"printing_merge_request_link_enabled"`
+
+	CIConfigPath               string `json:"ci_config_path"`
+	BuildTimeout               int    `json:"build_timeout"`
+	BuildGitStrategy           string `json:"build_git_strategy"`
+	AutoCancelPendingPipelines string `json:"auto_cancel_pending_pipelines"`
+	BuildCoverageRegex         string `json:"build_coverage_regex"`
+	AutoDevopsEnabled          bool   `json:"auto_devops_enabled"`
+	AutoDevopsDeployStrategy   string `json:"auto_devops_deploy_strategy"`
+	CIDefaultGitDepth          int    `json:"ci_default_git_depth"`
+
+	RepositoryAccessLevel    string `json:"repository_access_level"`
+	IssuesAccessLevel        string `json:"issues_access_level"`
+	MergeRequestsAccessLevel string `json:"merge_requests_access_level"`
+	ForkingAccessLevel       string `json:"forking_access_level"`
+	WikiAccessLevel          string `json:"wiki_access_level"`
+	SnippetsAccessLevel      string `json:"snippets_access_level"`
+	BuildsAccessLevel        string `json:"builds_access_level"`
+	PagesAccessLevel         string `json:"pages_access_level"`
+	OperationsAccessLevel    string `json:"operations_access_level"`
+}
+
+// gitlabProjectNamespace is a gitlab.ProjectNamespace along with its full
+// path.
+type gitlabProjectNamespace struct {
+	gitlab.ProjectNamespace
+
+	FullPath string `json:"full_path"`
+}
+
+// getProjectOptions represents the available getProject() options.
+type getProjectOptions struct {
+	Statistics *bool `url:"statistics,omitempty" json:"statistics,omitempty"`
+}
+
+// getProject gets a specific project, identified by project ID or full path.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#get-single-project
+func getProject(client *gitlab.Client, project string, opt *getProjectOptions, options ...gitlab.OptionFunc) (*gitlabProject, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s", url.QueryEscape(project))
+
+	req, err := client.NewRequest("GET", u, opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(gitlabProject)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
+
+// forkProjectOptions represents the available forkProject() options.
+type forkProjectOptions struct {
+	Namespace interface{} `url:"namespace,omitempty" json:"namespace,omitempty"`
+	Name      *string     `url:"name,omitempty" json:"name,omitempty"`
+	Path      *string     `url:"path,omitempty" json:"path,omitempty"`
+}
+
+// forkProject forks project into a namespace, given by ID or full path,
+// which defaults to the one of the authenticated user. Unlike go-gitlab's
+// ForkProject, it lets the fork be given a name and path.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#fork-project
+func forkProject(client *gitlab.Client, project string, opt *forkProjectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s/fork", url.QueryEscape(project))
+
+	req, err := client.NewRequest("POST", u, opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(gitlab.Project)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
+
+// projectOptions represents the available createProject() and editProject()
+// options, adding to the ones of go-gitlab those it does not support yet.
+type projectOptions struct {
+	gitlab.CreateProjectOptions
+
+	InitializeWithReadme *bool     `url:"initialize_with_readme,omitempty" json:"initialize_with_readme,omitempty"`
+	TagList              *[]string `url:"tag_list,omitempty" json:"tag_list,omitempty"`
+	Topics               *[]string `url:"topics,omitempty" json:"topics,omitempty"`
+	Avatar               *string   `url:"avatar,omitempty" json:"avatar,omitempty"`
+
+	TemplateName                *string `url:"template_name,omitempty" json:"template_name,omitempty"`
+	UseCustomTemplate           *bool   `url:"use_custom_template,omitempty" json:"use_custom_template,omitempty"`
+	GroupWithProjectTemplatesID *int    `url:"group_with_project_templates_id,omitempty" json:"group_with_project_templates_id,omitempty"`
+	TemplateProjectID           *int    `url:"template_project_id,omitempty" json:"template_project_id,omitempty"`
+
+	MergeMethod                     *string `url:"merge_method,omitempty" json:"merge_method,omitempty"`
+	RemoveSourceBranchAfterMerge    *bool   `url:"remove_source_branch_after_merge,omitempty" json:"remove_source_branch_after_merge,omitempty"`
+	SquashOption                    *string `url:"squash_option,omitempty" json:"squash_option,omitempty"`
+	PrintingMergeRequestLinkEnabled *bool   `url:"printing_merge_request_link_enabled,omitempty" json:"printing_merge_request_link_enabled,omitempty"`
+
+	CIConfigPath               *string `url:"ci_config_path,omitempty" json:"ci_config_path,omitempty"`
+	BuildTimeout               *int    `url:"build_timeout,omitempty" json:"build_timeout,omitempty"`
+	BuildGitStrategy           *string `url:"build_git_strategy,omitempty" json:"build_git_strategy,omitempty"`
+	AutoCancelPendingPipelines *string `url:"auto_cancel_pending_pipelines,omitempty" json:"auto_cancel_pending_pipelines,omitempty"`
+	BuildCoverageRegex         *string `url:"build_coverage_regex,omitempty" json:"build_coverage_regex,omitempty"`
+	AutoDevopsEnabled          *bool   `url:"auto_devops_enabled,omitempty" json:"auto_devops_enabled,omitempty"`
+	AutoDevopsDeployStrategy   *string `url:"auto_devops_deploy_strategy,omitempty" json:"auto_devops_deploy_strategy,omitempty"`
+	CIDefaultGitDepth          *int    `url:"ci_default_git_depth,omitempty" json:"ci_default_git_depth,omitempty"`
+
+	RepositoryAccessLevel    *string `url:"repository_access_level,omitempty" json:"repository_access_level,omitempty"`
+	IssuesAccessLevel        *string `url:"issues_access_level,omitempty" json:"issues_access_level,omitempty"`
+	MergeRequestsAccessLevel *string `url:"merge_requests_access_level,omitempty" json:"merge_requests_access_level,omitempty"`
+	ForkingAccessLevel       *string `url:"forking_access_level,omitempty" json:"forking_access_level,omitempty"`
+	WikiAccessLevel          *string `url:"wiki_access_level,omitempty" json:"wiki_access_level,omitempty"`
+	SnippetsAccessLevel      *string `url:"snippets_access_level,omitempty" json:"snippets_access_level,omitempty"`
+	BuildsAccessLevel        *string `url:"builds_access_level,omitempty" json:"builds_access_level,omitempty"`
+	PagesAccessLevel         *string `url:"pages_access_level,omitempty" json:"pages_access_level,omitempty"`
+	OperationsAccessLevel    *string `url:"operations_access_level,omitempty" json:"operations_access_level,omitempty"`
+}
+
+// createProject creates a new project owned by the authenticated user, or in
+// the namespace given in opt.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#create-project
+func createProject(client *gitlab.Client, opt *projectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	req, err := client.NewRequest("POST", "projects", opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(gitlab.Project)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
+
+// editProject updates an existing project, identified by project ID or full
+// path.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#edit-project
+func editProject(client *gitlab.Client, project string, opt *projectOptions, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s", url.QueryEscape(project))
+
+	req, err := client.NewRequest("PUT", u, opt, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := new(gitlab.Project)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
+
+// uploadProjectAvatar sets the avatar of project to the image in file. An
+// avatar is removed by editing the project with an empty Avatar instead.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/api/projects.html#upload-a-project-avatar
+func uploadProjectAvatar(client *gitlab.Client, project string, file string, options ...gitlab.OptionFunc) (*gitlab.Project, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s", url.QueryEscape(project))
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	b := &bytes.Buffer{}
+	w := multipart.NewWriter(b)
+
+	fw, err := w.CreateFormFile("avatar", filepath.Base(file))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = io.Copy(fw, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.Close()
+
+	req, err := client.NewRequest("", u, nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Body = ioutil.NopCloser(b)
+	req.ContentLength = int64(b.Len())
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Method = "PUT"
+
+	p := new(gitlab.Project)
+	resp, err := client.Do(req, p)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return p, resp, err
+}
@@ -0,0 +1,105 @@
+package gitlab
+
+import (
+	"testing"
+
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+func TestTransferProject(t *testing.T) {
+	server := newFakeGitLab()
+	defer server.Close()
+	client := server.client(t)
+
+	group, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("foo"), Path: gitlab.String("foo")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	project, _, err := client.Projects.CreateProject(&gitlab.CreateProjectOptions{Name: gitlab.String("bar")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	project, _, err = transferProject(client, project.PathWithNamespace, &transferProjectOptions{Namespace: group.FullPath})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if project.PathWithNamespace != "foo/bar" || project.Namespace.ID != group.ID {
+		t.Fatalf("got project %s in namespace %d; want foo/bar in %d", project.PathWithNamespace, project.Namespace.ID, group.ID)
+	}
+
+	_, _, err = transferProject(client, "foo/bar", &transferProjectOptions{Namespace: "missing"})
+	if !isNotFound(err) {
+		t.Fatalf("got error %v; want a 404", err)
+	}
+}
+
+func TestTransferGroup(t *testing.T) {
+	server := newFakeGitLab()
+	defer server.Close()
+	client := server.client(t)
+
+	foo, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("foo"), Path: gitlab.String("foo")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	bar, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("bar"), Path: gitlab.String("bar")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	group, _, err := transferGroup(client, "bar", &transferGroupOptions{GroupID: gitlab.Int(foo.ID)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if group.FullPath != "foo/bar" || group.ParentID != foo.ID {
+		t.Fatalf("got group %s with parent %d; want foo/bar with parent %d", group.FullPath, group.ParentID, foo.ID)
+	}
+
+	if _, _, err := transferGroup(client, "foo", &transferGroupOptions{GroupID: gitlab.Int(bar.ID)}); apiStatusCode(err) != 400 {
+		t.Fatalf("got error %v; want a 400 when moving a group under its own subgroup", err)
+	}
+
+	group, _, err = transferGroup(client, "foo/bar", &transferGroupOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if group.FullPath != "bar" || group.ParentID != 0 {
+		t.Fatalf("got group %s with parent %d; want a top level group", group.FullPath, group.ParentID)
+	}
+}
+
+func TestForkProject(t *testing.T) {
+	server := newFakeGitLab()
+	defer server.Close()
+	client := server.client(t)
+
+	group, _, err := client.Groups.CreateGroup(&gitlab.CreateGroupOptions{Name: gitlab.String("foo"), Path: gitlab.String("foo")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	source, _, err := client.Projects.CreateProject(&gitlab.CreateProjectOptions{Name: gitlab.String("bar")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fork, _, err := forkProject(client, source.PathWithNamespace, &forkProjectOptions{Namespace: group.ID})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fork.PathWithNamespace != "foo/bar" || fork.ForkedFromProject == nil || fork.ForkedFromProject.ID != source.ID {
+		t.Fatalf("got fork %s of %v; want foo/bar forked from %d", fork.PathWithNamespace, fork.ForkedFromProject, source.ID)
+	}
+
+	p, _, err := getProject(client, "foo/bar", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.ImportStatus == "none" {
+		t.Fatalf("got import status %q; want the fork to be in progress", p.ImportStatus)
+	}
+
+	if _, _, err := forkProject(client, source.PathWithNamespace, &forkProjectOptions{Namespace: "foo"}); !isConflict(err) {
+		t.Fatalf("got error %v; want a 409 when the path is taken", err)
+	}
+}
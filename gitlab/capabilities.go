@@ -0,0 +1,109 @@
+package gitlab
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// featureVersions records the first GitLab version shipping each feature the
+// provider relies on.
+var featureVersions = map[string]string{
+	"api_v4":           "9.0",
+	"project_transfer": "11.1",
+	"group_transfer":   "14.6",
+	"squash_option":    "13.2",
+
+	"auto_devops_deploy_strategy": "11.10",
+	"ci_default_git_depth":        "12.1",
+	"project_access_levels":       "12.4",
+	"operations_access_level":     "13.0",
+	"custom_project_templates":    "11.2",
+	"group_project_templates":     "11.6",
+}
+
+// parseServerVersion parses a version as reported by /version, e.g.
+// "10.1.0-ee". Edition and pre-release suffixes are dropped, so that
+// "10.1.0-ee" is not considered older than "10.1".
+func parseServerVersion(raw string) (*version.Version, error) {
+	v, err := version.NewVersion(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GitLab version %q: %s", raw, err)
+	}
+
+	segments := v.Segments()
+	return version.NewVersion(fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2]))
+}
+
+// serverVersion returns the version of the GitLab server. It is looked up at
+// most once, and only when first needed if credentials validation has been
+// skipped.
+func (m *providerMeta) serverVersion() (*version.Version, error) {
+	m.versionOnce.Do(func() {
+		if m.version != nil {
+			return
+		}
+
+		v, _, err := m.client.Version.GetVersion()
+		if err != nil {
+			m.versionErr = err
+			return
+		}
+		m.version, m.versionErr = parseServerVersion(v.Version)
+	})
+	return m.version, m.versionErr
+}
+
+// requireFeature returns an error explaining that what needs a newer GitLab
+// when the server is known to lack feature. When the server version cannot be
+// determined the feature is assumed to be available, and GitLab gets the
+// final say.
+func (m *providerMeta) requireFeature(feature, what string) error {
+	minimum, ok := featureVersions[feature]
+	if !ok {
+		return fmt.Errorf("unknown GitLab feature %q", feature)
+	}
+
+	current, err := m.serverVersion()
+	if err != nil {
+		log.Printf("[WARN] unable to determine the GitLab version, assuming it supports %s: %s", feature, err)
+		return nil
+	}
+
+	if current.LessThan(version.Must(version.NewVersion(minimum))) {
+		return fmt.Errorf("%s requires GitLab %s or later, but the server runs GitLab %s", what, minimum, current)
+	}
+	return nil
+}
+
+// checkAttributeFeatures returns an error when an attribute being set or
+// changed on d needs a feature the GitLab server lacks. attrs maps attribute
+// names to the feature they need.
+//
+// This version of Terraform gives providers no hook into planning, so
+// resources call it first thing in Create and Update: the error is reported
+// at apply time, but before any change is made.
+func checkAttributeFeatures(d *schema.ResourceData, meta interface{}, attrs map[string]string) error {
+	names := make([]string, 0, len(attrs))
+	for attr := range attrs {
+		names = append(names, attr)
+	}
+	sort.Strings(names)
+
+	for _, attr := range names {
+		if _, ok := d.GetOk(attr); !ok {
+			continue
+		}
+		if d.Id() != "" && !d.HasChange(attr) {
+			continue
+		}
+
+		if err := meta.(*providerMeta).requireFeature(attrs[attr], fmt.Sprintf("%q", attr)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
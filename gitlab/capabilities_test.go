@@ -0,0 +1,105 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	cases := []struct {
+		Raw     string
+		Version string
+	}{
+		{"9.0.0", "9.0.0"},
+		{"10.1.0-ee", "10.1.0"},
+		{"10.2.0-pre", "10.2.0"},
+		{"11.4", "11.4.0"},
+	}
+
+	for _, tc := range cases {
+		v, err := parseServerVersion(tc.Raw)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Raw, err)
+		}
+		if v.String() != tc.Version {
+			t.Fatalf("%s: got %s; want %s", tc.Raw, v, tc.Version)
+		}
+	}
+
+	if _, err := parseServerVersion("nightly"); err == nil {
+		t.Fatalf("expected an error for an unparseable version")
+	}
+}
+
+func TestProviderMeta_requireFeature(t *testing.T) {
+	featureVersions["test_feature"] = "10.1"
+	defer delete(featureVersions, "test_feature")
+
+	cases := []struct {
+		Server string
+		Error  string
+	}{
+		{Server: "10.1.0-ee"},
+		{Server: "11.0.0"},
+		{Server: "10.0.4", Error: "requires GitLab 10.1 or later, but the server runs GitLab 10.0.4"},
+		{Server: "8.17.0", Error: "terraform-gitlab-provider requires GitLab 9.0 or later"},
+		// /version cannot be read, so the feature is assumed to be available.
+		{Server: ""},
+	}
+
+	for _, tc := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/user":
+				fmt.Fprint(w, `{"id": 1, "username": "root", "is_admin": true}`)
+			case "/api/v4/version":
+				if tc.Server == "" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				fmt.Fprintf(w, `{"version": %q, "revision": "abcdef"}`, tc.Server)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		c := &Config{Token: "secret", BaseURL: server.URL + "/api/v4/"}
+		raw, err := c.Client()
+		if err == nil {
+			err = raw.(*providerMeta).requireFeature("test_feature", "test_feature")
+		}
+		server.Close()
+
+		if tc.Error == "" && err != nil {
+			t.Fatalf("%s: err: %s", tc.Server, err)
+		}
+		if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("%s: got error %v; want %q", tc.Server, err, tc.Error)
+		}
+	}
+}
+
+func TestCheckAttributeFeatures(t *testing.T) {
+	featureVersions["test_feature"] = "10.1"
+	defer delete(featureVersions, "test_feature")
+
+	meta := &providerMeta{}
+	meta.version, _ = parseServerVersion("10.0.0")
+	meta.versionOnce.Do(func() {})
+
+	d := resourceGitlabProject().TestResourceData()
+	attrs := map[string]string{"description": "test_feature"}
+
+	if err := checkAttributeFeatures(d, meta, attrs); err != nil {
+		t.Fatalf("an unset attribute should not be checked: %s", err)
+	}
+
+	d.Set("description", "foo")
+	err := checkAttributeFeatures(d, meta, attrs)
+	if err == nil || !strings.Contains(err.Error(), `"description" requires GitLab 10.1 or later`) {
+		t.Fatalf("got error %v; want a version error", err)
+	}
+}
@@ -3,22 +3,109 @@ package gitlab
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform/helper/logging"
 	"github.com/xanzy/go-gitlab"
 )
 
 // Config is per-provider, specifies where to connect to gitlab
 type Config struct {
-	Token      string
-	BaseURL    string
-	Insecure   bool
-	CACertFile string
+	Token        string
+	TokenType    string
+	TokenCommand string
+	BaseURL      string
+	Insecure     bool
+	CACertFile   string
+	ClientCert   string
+	ClientKey    string
+
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
+	MaxConcurrentRequests int
+	RequestsPerSecond     float64
+
+	SkipCredentialsValidation bool
+
+	Sudo string
+}
+
+// providerMeta is handed to every resource as its meta. It wraps the
+// *gitlab.Client together with what we know about the authenticated user.
+type providerMeta struct {
+	client *gitlab.Client
+
+	// sudo is the user resources act on behalf of by default.
+	sudo string
+
+	userOnce sync.Once
+	user     *gitlab.User
+	userErr  error
+
+	versionOnce sync.Once
+	version     *version.Version
+	versionErr  error
+}
+
+// currentUser returns the user the provider is authenticated as. The user is
+// looked up at most once, and only when first needed if credentials
+// validation has been skipped.
+func (m *providerMeta) currentUser() (*gitlab.User, error) {
+	m.userOnce.Do(func() {
+		if m.user != nil {
+			return
+		}
+		m.user, _, m.userErr = m.client.Users.CurrentUser()
+	})
+	return m.user, m.userErr
+}
+
+// requireAdmin returns an error explaining that action needs an administrator
+// when the authenticated user is known not to be one. Tokens which cannot
+// read their own user (e.g. job tokens) are let through, and GitLab gets the
+// final say.
+func (m *providerMeta) requireAdmin(action string) error {
+	user, err := m.currentUser()
+	if err != nil {
+		log.Printf("[WARN] unable to check whether the authenticated user is an administrator: %s", err)
+		return nil
+	}
+
+	if !user.IsAdmin {
+		return fmt.Errorf("%s requires an administrator, but the provider is authenticated as %q (id %d) who is not one", action, user.Username, user.ID)
+	}
+	return nil
 }
 
-// Client returns a *gitlab.Client to interact with the configured gitlab instance
+// Client returns a *providerMeta to interact with the configured gitlab instance
 func (c *Config) Client() (interface{}, error) {
+	if c.RetryWaitMin > c.RetryWaitMax {
+		return nil, fmt.Errorf("retry_wait_min (%s) must not be greater than retry_wait_max (%s)", c.RetryWaitMin, c.RetryWaitMax)
+	}
+
+	token := c.Token
+	if c.TokenCommand != "" {
+		var err error
+		token, err = runTokenCommand(c.TokenCommand)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("one of token or token_command must be set")
+	}
+
 	// Configure TLS/SSL
 	tlsConfig := &tls.Config{}
 
@@ -34,16 +121,57 @@ func (c *Config) Client() (interface{}, error) {
 		tlsConfig.RootCAs = caCertPool
 	}
 
+	// If a client certificate has been specified, present it to the server
+	if c.ClientCert != "" || c.ClientKey != "" {
+		cert, err := c.clientCertificate()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
 	// If configured as insecure, turn off SSL verification
 	if c.Insecure {
 		tlsConfig.InsecureSkipVerify = true
 	}
 
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	var transport http.RoundTripper = &http.Transport{TLSClientConfig: tlsConfig}
+
+	// At TRACE level, log every attempt made to talk to GitLab.
+	if logging.LogLevel() == "TRACE" {
+		transport = &traceTransport{transport: transport}
+	}
+
+	// Every resource shares this client, and so the same limits.
+	transport = newThrottleTransport(transport, c.MaxConcurrentRequests, c.RequestsPerSecond)
+
+	httpClient := &http.Client{
+		Transport: &errorBodyTransport{
+			transport: &retryTransport{
+				transport:  transport,
+				maxRetries: c.MaxRetries,
+				waitMin:    c.RetryWaitMin,
+				waitMax:    c.RetryWaitMax,
+			},
+		},
+	}
 
-	httpClient := &http.Client{Transport: transport}
+	var client *gitlab.Client
+	switch c.TokenType {
+	case "oauth":
+		client = gitlab.NewOAuthClient(httpClient, token)
+	case "job":
+		// go-gitlab only knows about private and OAuth tokens, so the
+		// JOB-TOKEN header is set by the transport instead.
+		httpClient.Transport = &jobTokenTransport{
+			transport: httpClient.Transport,
+			token:     token,
+		}
+		client = gitlab.NewClient(httpClient, "")
+	default:
+		client = gitlab.NewClient(httpClient, token)
+	}
 
-	client := gitlab.NewClient(httpClient, c.Token)
 	if c.BaseURL != "" {
 		err := client.SetBaseURL(c.BaseURL)
 		if err != nil {
@@ -52,11 +180,92 @@ func (c *Config) Client() (interface{}, error) {
 		}
 	}
 
+	meta := &providerMeta{
+		client: client,
+		sudo:   c.Sudo,
+	}
+
+	if c.SkipCredentialsValidation {
+		return meta, nil
+	}
+
 	// Test the credentials by checking we can get information about the authenticated user.
-	_, _, err := client.Users.CurrentUser()
+	user, _, err := client.Users.CurrentUser()
 	if err != nil {
 		return nil, err
 	}
+	meta.user = user
+
+	// Look up the server version once, so that resources can check whether
+	// the features they need are available. Tokens and proxies which cannot
+	// read /version are let through: features are then assumed to be
+	// available, and GitLab gets the final say.
+	if _, err := meta.serverVersion(); err != nil {
+		log.Printf("[WARN] unable to determine the GitLab version: %s", err)
+		return meta, nil
+	}
+
+	if err := meta.requireFeature("api_v4", "terraform-gitlab-provider"); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// clientCertificate loads the key pair used for mutual TLS authentication.
+func (c *Config) clientCertificate() (tls.Certificate, error) {
+	if c.ClientCert == "" || c.ClientKey == "" {
+		return tls.Certificate{}, fmt.Errorf("client_cert and client_key must be set together")
+	}
+
+	certPEM, err := readPEM(c.ClientCert)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error reading client_cert: %s", err)
+	}
 
-	return client, nil
+	keyPEM, err := readPEM(c.ClientKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error reading client_key: %s", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("client_cert and client_key are not a valid key pair: %s", err)
+	}
+
+	return cert, nil
+}
+
+// readPEM returns v itself when it holds inline PEM data, or the content of
+// the file v points to otherwise.
+func readPEM(v string) ([]byte, error) {
+	if strings.Contains(v, "-----BEGIN") {
+		return []byte(v), nil
+	}
+	return ioutil.ReadFile(v)
+}
+
+// runTokenCommand runs command through the shell and returns what it printed
+// on stdout, so that tokens can be fetched from a secrets manager.
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("error running token_command: %s: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("error running token_command: %s", err)
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token_command did not print a token")
+	}
+	return token, nil
 }
@@ -0,0 +1,186 @@
+package gitlab
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClientKeyPair returns a self-signed certificate and its private key,
+// both PEM encoded.
+func testClientKeyPair(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestConfig_clientCertificate(t *testing.T) {
+	certPEM, keyPEM := testClientKeyPair(t)
+	_, otherKeyPEM := testClientKeyPair(t)
+
+	dir, err := ioutil.TempDir("", "tf-gitlab")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	ioutil.WriteFile(certFile, []byte(certPEM), 0600)
+	ioutil.WriteFile(keyFile, []byte(keyPEM), 0600)
+
+	cases := []struct {
+		Cert  string
+		Key   string
+		Error string
+	}{
+		{Cert: certPEM, Key: keyPEM},
+		{Cert: certFile, Key: keyFile},
+		{Cert: certFile, Key: keyPEM},
+		{Cert: certPEM, Error: "must be set together"},
+		{Cert: certPEM, Key: otherKeyPEM, Error: "not a valid key pair"},
+		{Cert: filepath.Join(dir, "missing.crt"), Key: keyFile, Error: "error reading client_cert"},
+	}
+
+	for i, tc := range cases {
+		c := &Config{ClientCert: tc.Cert, ClientKey: tc.Key}
+		_, err := c.clientCertificate()
+		if tc.Error == "" && err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("%d: got error %v; want %q", i, err, tc.Error)
+		}
+	}
+}
+
+func TestRunTokenCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on a POSIX shell")
+	}
+
+	token, err := runTokenCommand("echo '  s3cr3t  '")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "s3cr3t" {
+		t.Fatalf("got token %q; want %q", token, "s3cr3t")
+	}
+
+	if _, err := runTokenCommand("true"); err == nil || !strings.Contains(err.Error(), "did not print a token") {
+		t.Fatalf("got error %v; want an empty output error", err)
+	}
+
+	if _, err := runTokenCommand("echo nope >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("got error %v; want the command stderr", err)
+	}
+}
+
+func TestProviderMeta_requireAdmin(t *testing.T) {
+	var isAdmin bool
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("PRIVATE-TOKEN") != "admin" && r.Header.Get("PRIVATE-TOKEN") != "user" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"id": 1, "username": "jdoe", "is_admin": %t}`, isAdmin)
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		Token   string
+		IsAdmin bool
+		Error   string
+	}{
+		{Token: "admin", IsAdmin: true},
+		{Token: "user", Error: `authenticated as "jdoe" (id 1) who is not one`},
+		// The user cannot be looked up, so GitLab gets to decide.
+		{Token: "job"},
+	}
+
+	for i, tc := range cases {
+		isAdmin = tc.IsAdmin
+		calls = 0
+
+		c := &Config{
+			Token:                     tc.Token,
+			BaseURL:                   server.URL + "/api/v4/",
+			SkipCredentialsValidation: true,
+		}
+		raw, err := c.Client()
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		meta := raw.(*providerMeta)
+		if calls != 0 {
+			t.Fatalf("%d: the user should not be looked up when credentials validation is skipped", i)
+		}
+
+		for j := 0; j < 2; j++ {
+			err = meta.requireAdmin("Creating a gitlab_user")
+			if tc.Error == "" && err != nil {
+				t.Fatalf("%d: err: %s", i, err)
+			}
+			if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+				t.Fatalf("%d: got error %v; want %q", i, err, tc.Error)
+			}
+		}
+		if calls != 1 {
+			t.Fatalf("%d: got %d calls to /user; want 1", i, calls)
+		}
+	}
+}
+
+func TestConfig_retryWait(t *testing.T) {
+	c := &Config{
+		Token:        "token",
+		BaseURL:      "https://gitlab.example.com/api/v4/",
+		RetryWaitMin: 10 * time.Second,
+		RetryWaitMax: 5 * time.Second,
+
+		SkipCredentialsValidation: true,
+	}
+
+	_, err := c.Client()
+	if err == nil || !strings.Contains(err.Error(), "retry_wait_min (10s) must not be greater than retry_wait_max (5s)") {
+		t.Fatalf("got error %v; want an error about retry_wait_min", err)
+	}
+}
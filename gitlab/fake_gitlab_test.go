@@ -0,0 +1,1638 @@
+package gitlab
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+// fakeGitLab is an in-memory implementation of the parts of the GitLab v4 API
+// used by the provider. Acceptance tests run against it when no real GitLab
+// instance is configured, see testAccPreCheck.
+//
+// Objects are kept as decoded JSON so that any attribute sent by the provider
+// is echoed back, the way GitLab does for the attributes it knows about.
+// Attributes GitLab derives, such as full paths and URLs, are computed when
+// an object is rendered.
+type fakeGitLab struct {
+	*httptest.Server
+
+	token   string
+	version string
+
+	mu         sync.Mutex
+	lastID     map[string]int
+	users      map[int]fakeObject
+	namespaces map[int]fakeObject // user namespaces; groups are namespaces too
+	groups     map[int]fakeObject
+	projects   map[int]fakeObject
+	branches   map[int][]string
+	imports    map[int][]string // next import statuses, by project
+	forks      map[int]int      // the project each fork was forked from
+	hooks      map[int]fakeObject
+	deployKeys map[int][]fakeObject // by project
+	labels     map[int][]fakeObject // by project
+}
+
+// fakeObject is a GitLab object as decoded from, or encoded to, JSON.
+type fakeObject map[string]interface{}
+
+func (o fakeObject) str(key string) string {
+	s, _ := o[key].(string)
+	return s
+}
+
+func (o fakeObject) int(key string) int {
+	switch v := o[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
+	}
+	return 0
+}
+
+func (o fakeObject) copy() fakeObject {
+	c := make(fakeObject, len(o))
+	for k, v := range o {
+		c[k] = v
+	}
+	return c
+}
+
+// merge copies params into o, leaving out the keys in skip.
+func (o fakeObject) merge(params fakeObject, skip ...string) {
+	for k, v := range params {
+		if !fakeContains(skip, k) {
+			o[k] = v
+		}
+	}
+}
+
+// newFakeGitLab starts a fake GitLab with a single administrator, root.
+func newFakeGitLab() *fakeGitLab {
+	s := &fakeGitLab{
+		token:      "fake-token",
+		version:    "15.0.0-ee",
+		lastID:     make(map[string]int),
+		users:      make(map[int]fakeObject),
+		namespaces: make(map[int]fakeObject),
+		groups:     make(map[int]fakeObject),
+		projects:   make(map[int]fakeObject),
+		branches:   make(map[int][]string),
+		imports:    make(map[int][]string),
+		forks:      make(map[int]int),
+		hooks:      make(map[int]fakeObject),
+		deployKeys: make(map[int][]fakeObject),
+		labels:     make(map[int][]fakeObject),
+	}
+	s.addUser(fakeObject{
+		"username": "root",
+		"name":     "Administrator",
+		"email":    "admin@example.com",
+		"is_admin": true,
+	})
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// client returns a go-gitlab client authenticated against s.
+func (s *fakeGitLab) client(t *testing.T) *gitlab.Client {
+	c := &Config{Token: s.token, BaseURL: s.URL + "/api/v4/"}
+	meta, err := c.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return meta.(*providerMeta).client
+}
+
+func (s *fakeGitLab) nextID(kind string) int {
+	s.lastID[kind]++
+	return s.lastID[kind]
+}
+
+func (s *fakeGitLab) now() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func (s *fakeGitLab) addUser(user fakeObject) fakeObject {
+	user["id"] = s.nextID("users")
+	user["state"] = "active"
+	user["created_at"] = s.now()
+	s.users[user.int("id")] = user
+
+	id := s.nextID("namespaces")
+	s.namespaces[id] = fakeObject{
+		"id":       id,
+		"name":     user.str("username"),
+		"path":     user.str("username"),
+		"kind":     "user",
+		"owner_id": user.int("id"),
+	}
+	return user
+}
+
+func (s *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.URL.EscapedPath(), "/api/v4/") {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+
+	var path []string
+	for _, segment := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/"), "/") {
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			s.fail(w, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		path = append(path, segment)
+	}
+
+	params, err := fakeParams(r)
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "error", err.Error())
+		return
+	}
+
+	actor, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	req := &fakeRequest{Request: r, path: path, params: params, actor: actor}
+	switch path[0] {
+	case "user":
+		s.serveCurrentUser(w, req)
+	case "version":
+		s.json(w, http.StatusOK, fakeObject{"version": s.version, "revision": "fake"})
+	case "users":
+		s.serveUsers(w, req)
+	case "groups":
+		s.serveGroups(w, req)
+	case "projects":
+		s.serveProjects(w, req)
+	default:
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+	}
+}
+
+// fakeRequest is an API request, along with its parameters and the user it
+// is made on behalf of.
+type fakeRequest struct {
+	*http.Request
+	path   []string
+	params fakeObject
+	actor  fakeObject
+}
+
+// route reports whether req is a method request on a path matching pattern,
+// where "*" matches any segment.
+func (req *fakeRequest) route(method string, pattern ...string) bool {
+	if req.Method != method || len(req.path) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != req.path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeParams returns the parameters of r, taken from its query string and
+// its JSON or form encoded body. Uploaded files are given as objects with
+// their filename and content.
+func fakeParams(r *http.Request) (fakeObject, error) {
+	params := fakeObject{}
+	for k, v := range r.URL.Query() {
+		params[k] = v[0]
+	}
+
+	switch {
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "application/json"):
+		var body fakeObject
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err.Error() != "EOF" {
+			return nil, fmt.Errorf("invalid JSON body: %s", err)
+		}
+		params.merge(body)
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data"):
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+		for k, v := range r.MultipartForm.File {
+			f, err := v[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			params[k] = fakeObject{"filename": v[0].Filename, "content": string(content)}
+		}
+	case r.Method == "POST" || r.Method == "PUT":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for k, v := range r.PostForm {
+			params[k] = v[0]
+		}
+	}
+	return params, nil
+}
+
+// authenticate returns the user r is made on behalf of, taking the Sudo
+// header into account.
+func (s *fakeGitLab) authenticate(w http.ResponseWriter, r *http.Request) (fakeObject, bool) {
+	token := r.Header.Get("PRIVATE-TOKEN")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		token = r.Header.Get("JOB-TOKEN")
+	}
+	if token != s.token {
+		s.fail(w, http.StatusUnauthorized, "message", "401 Unauthorized")
+		return nil, false
+	}
+
+	root := s.users[1]
+	sudo := r.Header.Get("Sudo")
+	if sudo == "" {
+		return root, true
+	}
+	for _, user := range s.sortedObjects(s.users) {
+		if strconv.Itoa(user.int("id")) == sudo || user.str("username") == sudo {
+			return user, true
+		}
+	}
+	s.fail(w, http.StatusNotFound, "message", fmt.Sprintf("404 User with ID or username '%s' Not Found", sudo))
+	return nil, false
+}
+
+func (s *fakeGitLab) json(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+// fail writes an error the way GitLab does, either as {"message": ...} or
+// {"error": ...}.
+func (s *fakeGitLab) fail(w http.ResponseWriter, status int, key string, message interface{}) {
+	s.json(w, status, fakeObject{key: message})
+}
+
+func (s *fakeGitLab) notFound(w http.ResponseWriter, what string) {
+	s.fail(w, http.StatusNotFound, "message", fmt.Sprintf("404 %s Not Found", what))
+}
+
+// missing fails with a 400 if any of names is not a parameter of req.
+func (s *fakeGitLab) missing(w http.ResponseWriter, req *fakeRequest, names ...string) bool {
+	var missing []string
+	for _, name := range names {
+		if v, ok := req.params[name]; !ok || v == nil || v == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return false
+	}
+
+	verb := "is"
+	if len(missing) > 1 {
+		verb = "are"
+	}
+	s.fail(w, http.StatusBadRequest, "error", fmt.Sprintf("%s %s missing", strings.Join(missing, ", "), verb))
+	return true
+}
+
+// page writes a page of items, with the pagination headers set by GitLab.
+func (s *fakeGitLab) page(w http.ResponseWriter, req *fakeRequest, items []fakeObject) {
+	page, _ := strconv.Atoi(req.params.str("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(req.params.str("per_page"))
+	if perPage < 1 {
+		perPage = 20
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	if items == nil {
+		items = []fakeObject{}
+	}
+	total := len(items)
+	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	link := func(page int) string {
+		q := req.URL.Query()
+		q.Set("page", strconv.Itoa(page))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return fmt.Sprintf("<%s%s?%s>", s.URL, req.URL.EscapedPath(), q.Encode())
+	}
+
+	links := []string{link(1) + `; rel="first"`, link(totalPages) + `; rel="last"`}
+	h := w.Header()
+	h.Set("X-Page", strconv.Itoa(page))
+	h.Set("X-Per-Page", strconv.Itoa(perPage))
+	h.Set("X-Total", strconv.Itoa(total))
+	h.Set("X-Total-Pages", strconv.Itoa(totalPages))
+	h.Set("X-Prev-Page", "")
+	h.Set("X-Next-Page", "")
+	if page > 1 {
+		h.Set("X-Prev-Page", strconv.Itoa(page-1))
+		links = append([]string{link(page-1) + `; rel="prev"`}, links...)
+	}
+	if page < totalPages {
+		h.Set("X-Next-Page", strconv.Itoa(page+1))
+		links = append([]string{link(page+1) + `; rel="next"`}, links...)
+	}
+	h.Set("Link", strings.Join(links, ", "))
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	s.json(w, http.StatusOK, items[start:end])
+}
+
+// sortedIDs returns the IDs of the objects of m in ascending order.
+func (s *fakeGitLab) sortedIDs(m map[int]fakeObject) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// sortedObjects returns the objects of m ordered by ID.
+func (s *fakeGitLab) sortedObjects(m map[int]fakeObject) []fakeObject {
+	objects := make([]fakeObject, 0, len(m))
+	for _, id := range s.sortedIDs(m) {
+		objects = append(objects, m[id])
+	}
+	return objects
+}
+
+// lookup finds an object of m by ID, or by full path when path is not nil.
+func (s *fakeGitLab) lookup(m map[int]fakeObject, id string, path func(fakeObject) string) fakeObject {
+	if n, err := strconv.Atoi(id); err == nil {
+		return m[n]
+	}
+	if path == nil {
+		return nil
+	}
+	for _, o := range s.sortedObjects(m) {
+		if strings.EqualFold(path(o), id) {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *fakeGitLab) serveCurrentUser(w http.ResponseWriter, req *fakeRequest) {
+	if !req.route("GET", "user") {
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+		return
+	}
+	s.json(w, http.StatusOK, s.renderUser(req.actor))
+}
+
+func (s *fakeGitLab) renderUser(user fakeObject) fakeObject {
+	u := user.copy()
+	u["web_url"] = s.URL + "/" + user.str("username")
+	u["avatar_url"] = nil
+	return u
+}
+
+// userWriteOnly lists the user parameters GitLab never returns.
+var userWriteOnly = []string{"id", "admin", "password", "reset_password", "skip_confirmation"}
+
+func (s *fakeGitLab) serveUsers(w http.ResponseWriter, req *fakeRequest) {
+	if req.Method != "GET" && req.actor["is_admin"] != true {
+		s.fail(w, http.StatusForbidden, "message", "403 Forbidden")
+		return
+	}
+
+	if req.route("GET", "users") {
+		var users []fakeObject
+		for _, user := range s.sortedObjects(s.users) {
+			if v := req.params.str("username"); v != "" && !strings.EqualFold(v, user.str("username")) {
+				continue
+			}
+			users = append(users, s.renderUser(user))
+		}
+		s.page(w, req, users)
+		return
+	}
+
+	if req.route("POST", "users") {
+		if s.missing(w, req, "email", "name", "username", "password") {
+			return
+		}
+		if ok := s.validateUser(w, req, nil); !ok {
+			return
+		}
+
+		user := fakeObject{
+			"projects_limit":   100000,
+			"can_create_group": true,
+			"is_admin":         false,
+		}
+		user.merge(req.params, userWriteOnly...)
+		if v, ok := req.params["admin"]; ok {
+			user["is_admin"] = v
+		}
+		s.addUser(user)
+		s.json(w, http.StatusCreated, s.renderUser(user))
+		return
+	}
+
+	if len(req.path) != 2 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	user := s.lookup(s.users, req.path[1], nil)
+	if user == nil {
+		s.notFound(w, "User")
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		s.json(w, http.StatusOK, s.renderUser(user))
+	case "PUT":
+		if ok := s.validateUser(w, req, user); !ok {
+			return
+		}
+		user.merge(req.params, userWriteOnly...)
+		if v, ok := req.params["admin"]; ok {
+			user["is_admin"] = v
+		}
+		if v, ok := req.params["username"]; ok {
+			s.userNamespace(user)["path"] = v
+			s.userNamespace(user)["name"] = v
+		}
+		s.json(w, http.StatusOK, s.renderUser(user))
+	case "DELETE":
+		ns := s.userNamespace(user)
+		for _, project := range s.sortedObjects(s.projects) {
+			if project.int("namespace_id") == ns.int("id") {
+				s.deleteProject(project)
+			}
+		}
+		delete(s.namespaces, ns.int("id"))
+		delete(s.users, user.int("id"))
+		s.json(w, http.StatusNoContent, nil)
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+	}
+}
+
+// validateUser checks the uniqueness of the username and email of a user
+// being created, or of user when it is being updated.
+func (s *fakeGitLab) validateUser(w http.ResponseWriter, req *fakeRequest, user fakeObject) bool {
+	for _, other := range s.users {
+		if user != nil && other.int("id") == user.int("id") {
+			continue
+		}
+		if v := req.params.str("username"); v != "" && strings.EqualFold(v, other.str("username")) {
+			s.fail(w, http.StatusConflict, "message", "Username has already been taken")
+			return false
+		}
+		if v := req.params.str("email"); v != "" && strings.EqualFold(v, other.str("email")) {
+			s.fail(w, http.StatusConflict, "message", "Email has already been taken")
+			return false
+		}
+	}
+
+	if v, ok := req.params["password"]; ok && len(fmt.Sprint(v)) < 8 {
+		s.fail(w, http.StatusBadRequest, "message", fakeObject{
+			"password": []string{"is too short (minimum is 8 characters)"},
+		})
+		return false
+	}
+	return true
+}
+
+func (s *fakeGitLab) userNamespace(user fakeObject) fakeObject {
+	for _, ns := range s.namespaces {
+		if ns.int("owner_id") == user.int("id") {
+			return ns
+		}
+	}
+	return nil
+}
+
+// namespace returns the namespace with the given ID, which is either a group
+// or the namespace of a user.
+func (s *fakeGitLab) namespace(id int) fakeObject {
+	if group, ok := s.groups[id]; ok {
+		return fakeObject{
+			"id":        id,
+			"name":      group.str("name"),
+			"path":      group.str("path"),
+			"kind":      "group",
+			"full_path": s.groupFullPath(group),
+			"parent_id": group["parent_id"],
+		}
+	}
+	if ns, ok := s.namespaces[id]; ok {
+		n := ns.copy()
+		delete(n, "owner_id")
+		n["full_path"] = ns.str("path")
+		n["parent_id"] = nil
+		return n
+	}
+	return nil
+}
+
+func (s *fakeGitLab) groupFullPath(group fakeObject) string {
+	if parent, ok := s.groups[group.int("parent_id")]; ok {
+		return s.groupFullPath(parent) + "/" + group.str("path")
+	}
+	return group.str("path")
+}
+
+func (s *fakeGitLab) groupFullName(group fakeObject) string {
+	if parent, ok := s.groups[group.int("parent_id")]; ok {
+		return s.groupFullName(parent) + " / " + group.str("name")
+	}
+	return group.str("name")
+}
+
+func (s *fakeGitLab) renderGroup(group fakeObject) fakeObject {
+	g := group.copy()
+	g["full_path"] = s.groupFullPath(group)
+	g["full_name"] = s.groupFullName(group)
+	g["web_url"] = s.URL + "/groups/" + s.groupFullPath(group)
+	g["avatar_url"] = nil
+	return g
+}
+
+// pathTaken reports whether path is used by another namespace under parent,
+// 0 being the top level.
+func (s *fakeGitLab) pathTaken(path string, parent int, except int) bool {
+	for id, group := range s.groups {
+		if id != except && group.int("parent_id") == parent && strings.EqualFold(group.str("path"), path) {
+			return true
+		}
+	}
+	if parent == 0 {
+		for _, ns := range s.namespaces {
+			if strings.EqualFold(ns.str("path"), path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *fakeGitLab) serveGroups(w http.ResponseWriter, req *fakeRequest) {
+	if req.route("GET", "groups") {
+		var groups []fakeObject
+		for _, group := range s.sortedObjects(s.groups) {
+			if v := req.params.str("search"); v != "" && !strings.Contains(group.str("name"), v) && !strings.Contains(group.str("path"), v) {
+				continue
+			}
+			groups = append(groups, s.renderGroup(group))
+		}
+		s.page(w, req, groups)
+		return
+	}
+
+	if req.route("POST", "groups") {
+		if s.missing(w, req, "name", "path") {
+			return
+		}
+		parent := req.params.int("parent_id")
+		if _, ok := s.groups[parent]; parent != 0 && !ok {
+			s.notFound(w, "Group")
+			return
+		}
+		if !s.validVisibility(w, req) {
+			return
+		}
+		if s.pathTaken(req.params.str("path"), parent, 0) {
+			s.fail(w, http.StatusBadRequest, "message", fakeObject{"path": []string{"has already been taken"}})
+			return
+		}
+
+		group := fakeObject{
+			"description":            "",
+			"visibility":             "private",
+			"lfs_enabled":            true,
+			"request_access_enabled": false,
+			"parent_id":              nil,
+			"created_at":             s.now(),
+		}
+		group.merge(req.params)
+		if parent == 0 {
+			group["parent_id"] = nil
+		}
+		group["id"] = s.nextID("namespaces")
+		s.groups[group.int("id")] = group
+		s.json(w, http.StatusCreated, s.renderGroup(group))
+		return
+	}
+
+	if len(req.path) < 2 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	group := s.lookup(s.groups, req.path[1], s.groupFullPath)
+	if group == nil {
+		s.notFound(w, "Group")
+		return
+	}
+
+	if req.route("POST", "groups", "*", "transfer") {
+		s.serveGroupTransfer(w, req, group)
+		return
+	}
+	if len(req.path) != 2 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		s.json(w, http.StatusOK, s.renderGroup(group))
+	case "PUT":
+		if !s.validVisibility(w, req) {
+			return
+		}
+		if v := req.params.str("path"); v != "" && s.pathTaken(v, group.int("parent_id"), group.int("id")) {
+			s.fail(w, http.StatusBadRequest, "message", fakeObject{"path": []string{"has already been taken"}})
+			return
+		}
+		// Groups are moved through the transfer API, not updated.
+		group.merge(req.params, "id", "parent_id")
+		s.json(w, http.StatusOK, s.renderGroup(group))
+	case "DELETE":
+		s.deleteGroup(group)
+		s.fail(w, http.StatusAccepted, "message", "202 Accepted")
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+	}
+}
+
+func (s *fakeGitLab) serveGroupTransfer(w http.ResponseWriter, req *fakeRequest, group fakeObject) {
+	parent := req.params.int("group_id")
+	if parent != 0 {
+		if _, ok := s.groups[parent]; !ok {
+			s.notFound(w, "Group")
+			return
+		}
+	}
+
+	for ancestor := s.groups[parent]; ancestor != nil; ancestor = s.groups[ancestor.int("parent_id")] {
+		if ancestor.int("id") == group.int("id") {
+			s.fail(w, http.StatusBadRequest, "message", "Transfer failed: Cannot transfer group to one of its subgroup.")
+			return
+		}
+	}
+	if s.pathTaken(group.str("path"), parent, group.int("id")) {
+		s.fail(w, http.StatusBadRequest, "message", "Transfer failed: The parent group already has a subgroup or a project with the same path.")
+		return
+	}
+
+	group["parent_id"] = nil
+	if parent != 0 {
+		group["parent_id"] = parent
+	}
+	s.json(w, http.StatusCreated, s.renderGroup(group))
+}
+
+// deleteGroup deletes group along with its subgroups and projects.
+func (s *fakeGitLab) deleteGroup(group fakeObject) {
+	for _, subgroup := range s.sortedObjects(s.groups) {
+		if subgroup.int("parent_id") == group.int("id") {
+			s.deleteGroup(subgroup)
+		}
+	}
+	for _, project := range s.sortedObjects(s.projects) {
+		if project.int("namespace_id") == group.int("id") {
+			s.deleteProject(project)
+		}
+	}
+	delete(s.groups, group.int("id"))
+}
+
+func (s *fakeGitLab) validVisibility(w http.ResponseWriter, req *fakeRequest) bool {
+	return s.validValue(w, req, "visibility", "private", "internal", "public")
+}
+
+// validValue checks the parameter name, when given, is one of values.
+func (s *fakeGitLab) validValue(w http.ResponseWriter, req *fakeRequest, name string, values ...string) bool {
+	if req.params.str(name) == "" || fakeContains(values, req.params.str(name)) {
+		return true
+	}
+	s.fail(w, http.StatusBadRequest, "error", name+" does not have a valid value")
+	return false
+}
+
+// validProjectSettings checks the settings of a project which only take a
+// few values.
+func (s *fakeGitLab) validProjectSettings(w http.ResponseWriter, req *fakeRequest) bool {
+	return s.validVisibility(w, req) &&
+		s.validValue(w, req, "merge_method", "merge", "rebase_merge", "ff") &&
+		s.validValue(w, req, "squash_option", "never", "always", "default_on", "default_off") &&
+		s.validValue(w, req, "build_git_strategy", "fetch", "clone") &&
+		s.validValue(w, req, "auto_cancel_pending_pipelines", "enabled", "disabled") &&
+		s.validValue(w, req, "auto_devops_deploy_strategy", "continuous", "manual", "timed_incremental") &&
+		s.validAccessLevels(w, req)
+}
+
+// projectFeatures maps the features of a project which have an access level
+// to the boolean GitLab keeps alongside, if any.
+var projectFeatures = map[string]string{
+	"repository":     "",
+	"issues":         "issues_enabled",
+	"merge_requests": "merge_requests_enabled",
+	"forking":        "",
+	"wiki":           "wiki_enabled",
+	"snippets":       "snippets_enabled",
+	"builds":         "jobs_enabled",
+	"pages":          "",
+	"operations":     "",
+}
+
+func (s *fakeGitLab) validAccessLevels(w http.ResponseWriter, req *fakeRequest) bool {
+	for feature := range projectFeatures {
+		levels := []string{"disabled", "private", "enabled"}
+		if feature == "pages" {
+			levels = append(levels, "public")
+		}
+		if !s.validValue(w, req, feature+"_access_level", levels...) {
+			return false
+		}
+	}
+	return true
+}
+
+// validTemplate checks the template a project is created from, if any,
+// exists. Custom templates must be projects of the group given in
+// group_with_project_templates_id, or of its subgroups.
+func (s *fakeGitLab) validTemplate(w http.ResponseWriter, req *fakeRequest) bool {
+	if v := req.params.str("template_name"); v != "" && !fakeContains(projectTemplates, v) {
+		s.fail(w, http.StatusBadRequest, "message", fakeObject{"template_name": []string{fmt.Sprintf("'%s' is unknown or invalid", v)}})
+		return false
+	}
+
+	if _, ok := req.params["template_project_id"]; !ok {
+		return true
+	}
+	template := s.projects[req.params.int("template_project_id")]
+	if req.params["use_custom_template"] != true || template == nil {
+		s.fail(w, http.StatusBadRequest, "message", fakeObject{"template_project_id": []string{"is unknown or invalid"}})
+		return false
+	}
+	if _, ok := req.params["group_with_project_templates_id"]; ok {
+		group := s.groups[req.params.int("group_with_project_templates_id")]
+		if group == nil || !strings.HasPrefix(s.projectFullPath(template), s.groupFullPath(group)+"/") {
+			s.fail(w, http.StatusBadRequest, "message", fakeObject{"template_project_id": []string{"is unknown or invalid"}})
+			return false
+		}
+	}
+	return true
+}
+
+// syncProjectTopics sets the topics of project from params, given as topics
+// or, like before GitLab 14.0, as tag_list.
+func (s *fakeGitLab) syncProjectTopics(project fakeObject, params fakeObject) {
+	for _, key := range []string{"tag_list", "topics"} {
+		if v, ok := params[key]; ok {
+			project["topics"] = v
+		}
+	}
+	delete(project, "tag_list")
+}
+
+// projectStatistics returns the statistics of project, whose sizes grow with
+// the number of branches.
+func (s *fakeGitLab) projectStatistics(project fakeObject) fakeObject {
+	branches := len(s.branches[project.int("id")])
+	return fakeObject{
+		"commit_count":       branches,
+		"storage_size":       7168 * branches,
+		"repository_size":    4096 * branches,
+		"lfs_objects_size":   2048 * branches,
+		"job_artifacts_size": 1024 * branches,
+	}
+}
+
+// syncProjectFeatures updates the access levels of project from the
+// booleans given in params, or the booleans from the access levels, which
+// win when both are given.
+func (s *fakeGitLab) syncProjectFeatures(project fakeObject, params fakeObject) {
+	for feature, enabled := range projectFeatures {
+		level := feature + "_access_level"
+		if _, ok := params[level]; ok {
+			if enabled != "" {
+				project[enabled] = project.str(level) != "disabled"
+			}
+			continue
+		}
+		if _, ok := params[enabled]; ok && enabled != "" {
+			if project[enabled] == true {
+				project[level] = "enabled"
+			} else {
+				project[level] = "disabled"
+			}
+		}
+	}
+}
+
+func (s *fakeGitLab) projectFullPath(project fakeObject) string {
+	return s.namespace(project.int("namespace_id")).str("full_path") + "/" + project.str("path")
+}
+
+func (s *fakeGitLab) renderProject(project fakeObject) fakeObject {
+	p := project.copy()
+	delete(p, "namespace_id")
+
+	ns := s.namespace(project.int("namespace_id"))
+	fullPath := s.projectFullPath(project)
+	host := strings.TrimPrefix(s.URL, "http://")
+
+	p["namespace"] = ns
+	p["path_with_namespace"] = fullPath
+	p["name_with_namespace"] = strings.Replace(ns.str("full_path"), "/", " / ", -1) + " / " + project.str("name")
+	p["web_url"] = s.URL + "/" + fullPath
+	p["http_url_to_repo"] = s.URL + "/" + fullPath + ".git"
+	p["ssh_url_to_repo"] = "git@" + host + ":" + fullPath + ".git"
+	p["tag_list"] = project["topics"]
+	p["runners_token"] = fmt.Sprintf("GR1348941fake%d", project.int("id"))
+
+	if parent, ok := s.projects[s.forks[project.int("id")]]; ok {
+		r := s.renderProject(parent)
+		p["forked_from_project"] = fakeObject{
+			"id":                  r["id"],
+			"name":                r["name"],
+			"path":                r["path"],
+			"name_with_namespace": r["name_with_namespace"],
+			"path_with_namespace": r["path_with_namespace"],
+			"web_url":             r["web_url"],
+			"http_url_to_repo":    r["http_url_to_repo"],
+		}
+	}
+	return p
+}
+
+// startImport schedules filling the repository of project, from importURL or
+// as a fork. The import progresses each time the project is read, and fails
+// for URLs on unreachable.example.com.
+func (s *fakeGitLab) startImport(project fakeObject, importURL string) {
+	project["import_status"] = "scheduled"
+	if strings.Contains(importURL, "unreachable.example.com") {
+		s.imports[project.int("id")] = []string{"started", "failed"}
+	} else {
+		s.imports[project.int("id")] = []string{"started", "finished"}
+	}
+}
+
+func (s *fakeGitLab) advanceImport(project fakeObject) {
+	id := project.int("id")
+	if len(s.imports[id]) == 0 {
+		return
+	}
+
+	project["import_status"] = s.imports[id][0]
+	s.imports[id] = s.imports[id][1:]
+
+	switch project["import_status"] {
+	case "finished":
+		s.branches[id] = []string{"master"}
+		project["default_branch"] = "master"
+	case "failed":
+		project["import_error"] = "Error importing repository into " + s.projectFullPath(project) + " - Unable to access repository"
+	}
+}
+
+// projectPathPattern matches the characters GitLab replaces when deriving a
+// project path from its name.
+var projectPathPattern = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// projectWriteOnly lists the project parameters GitLab never returns.
+var projectWriteOnly = []string{
+	"id", "import_url", "initialize_with_readme", "avatar",
+	"template_name", "use_custom_template", "group_with_project_templates_id", "template_project_id",
+}
+
+// projectTemplates lists the built-in project templates.
+var projectTemplates = []string{"rails", "spring", "express", "iosswift", "dotnetcore", "android", "gomicro", "hugo", "jekyll", "plainhtml"}
+
+// validateProject checks the name and path of project are unique in its
+// namespace, reporting both the way GitLab does with the given status.
+func (s *fakeGitLab) validateProject(w http.ResponseWriter, project fakeObject, status int) bool {
+	errors := fakeObject{}
+	for id, other := range s.projects {
+		if id == project.int("id") || other.int("namespace_id") != project.int("namespace_id") {
+			continue
+		}
+		if strings.EqualFold(other.str("name"), project.str("name")) {
+			errors["name"] = []string{"has already been taken"}
+		}
+		if strings.EqualFold(other.str("path"), project.str("path")) {
+			errors["path"] = []string{"has already been taken"}
+		}
+	}
+	if len(errors) > 0 {
+		s.fail(w, status, "message", errors)
+		return false
+	}
+	return true
+}
+
+func (s *fakeGitLab) serveProjects(w http.ResponseWriter, req *fakeRequest) {
+	if req.route("GET", "projects") {
+		var projects []fakeObject
+		for _, project := range s.sortedObjects(s.projects) {
+			if v := req.params.str("search"); v != "" && !strings.Contains(project.str("name"), v) {
+				continue
+			}
+			projects = append(projects, s.renderProject(project))
+		}
+		s.page(w, req, projects)
+		return
+	}
+
+	if req.route("POST", "projects") {
+		if req.params.str("name") == "" && req.params.str("path") == "" {
+			s.fail(w, http.StatusBadRequest, "error", "name, path are missing, at least one parameter must be provided")
+			return
+		}
+		if !s.validProjectSettings(w, req) {
+			return
+		}
+
+		project := fakeObject{
+			"import_status":          "none",
+			"import_error":           nil,
+			"description":            "",
+			"default_branch":         nil,
+			"visibility":             "private",
+			"issues_enabled":         true,
+			"merge_requests_enabled": true,
+			"wiki_enabled":           true,
+			"snippets_enabled":       true,
+			"jobs_enabled":           true,
+			"lfs_enabled":            true,
+			"request_access_enabled": false,
+			"archived":               false,
+
+			"merge_method":                                     "merge",
+			"only_allow_merge_if_pipeline_succeeds":            false,
+			"only_allow_merge_if_all_discussions_are_resolved": false,
+			"remove_source_branch_after_merge":                 true,
+			"squash_option":                                    "default_off",
+			"printing_merge_request_link_enabled":              true,
+
+			"ci_config_path":                nil,
+			"build_timeout":                 3600,
+			"build_git_strategy":            "fetch",
+			"auto_cancel_pending_pipelines": "enabled",
+			"build_coverage_regex":          nil,
+			"public_jobs":                   true,
+			"shared_runners_enabled":        true,
+			"auto_devops_enabled":           true,
+			"auto_devops_deploy_strategy":   "continuous",
+			"ci_default_git_depth":          50,
+
+			"repository_access_level":     "enabled",
+			"issues_access_level":         "enabled",
+			"merge_requests_access_level": "enabled",
+			"forking_access_level":        "enabled",
+			"wiki_access_level":           "enabled",
+			"snippets_access_level":       "enabled",
+			"builds_access_level":         "enabled",
+			"pages_access_level":          "enabled",
+			"operations_access_level":     "enabled",
+			"container_registry_enabled":  true,
+
+			"topics":            []string{},
+			"avatar_url":        nil,
+			"star_count":        0,
+			"forks_count":       0,
+			"open_issues_count": 0,
+			"creator_id":        req.actor.int("id"),
+			"created_at":        s.now(),
+			"last_activity_at":  s.now(),
+		}
+		project.merge(req.params, projectWriteOnly...)
+		s.syncProjectFeatures(project, req.params)
+		s.syncProjectTopics(project, req.params)
+		if project.str("path") == "" {
+			project["path"] = strings.Trim(projectPathPattern.ReplaceAllString(strings.ToLower(project.str("name")), "-"), "-")
+		}
+		if project.str("name") == "" {
+			project["name"] = project.str("path")
+		}
+
+		if _, ok := req.params["namespace_id"]; !ok {
+			project["namespace_id"] = s.userNamespace(req.actor).int("id")
+		}
+		if s.namespace(project.int("namespace_id")) == nil {
+			s.fail(w, http.StatusBadRequest, "message", fakeObject{"namespace": []string{"is not valid"}})
+			return
+		}
+		if !s.validateProject(w, project, http.StatusBadRequest) {
+			return
+		}
+
+		if !s.validTemplate(w, req) {
+			return
+		}
+
+		// Without a repository there is no branch to be the default one.
+		project["default_branch"] = nil
+
+		project["id"] = s.nextID("projects")
+		s.projects[project.int("id")] = project
+
+		if req.params["initialize_with_readme"] == true {
+			branch := req.params.str("default_branch")
+			if branch == "" {
+				branch = "master"
+			}
+			s.branches[project.int("id")] = []string{branch}
+			project["default_branch"] = branch
+		}
+		if v := req.params.str("import_url"); v != "" {
+			s.startImport(project, v)
+		}
+		if _, ok := req.params["template_project_id"]; ok || req.params.str("template_name") != "" {
+			s.startImport(project, "")
+		}
+		s.json(w, http.StatusCreated, s.renderProject(project))
+		return
+	}
+
+	if len(req.path) < 2 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	project := s.lookup(s.projects, req.path[1], s.projectFullPath)
+	if project == nil {
+		s.notFound(w, "Project")
+		return
+	}
+
+	if len(req.path) > 2 {
+		switch req.path[2] {
+		case "hooks":
+			s.serveHooks(w, req, project)
+		case "deploy_keys":
+			s.serveDeployKeys(w, req, project)
+		case "labels":
+			s.serveLabels(w, req, project)
+		case "transfer":
+			s.serveProjectTransfer(w, req, project)
+		case "repository":
+			s.serveBranches(w, req, project)
+		case "fork":
+			s.serveProjectFork(w, req, project)
+		case "archive", "unarchive":
+			if !req.route("POST", "projects", "*", req.path[2]) {
+				s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+				return
+			}
+			project["archived"] = req.path[2] == "archive"
+			s.json(w, http.StatusCreated, s.renderProject(project))
+		default:
+			s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		}
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		s.advanceImport(project)
+		p := s.renderProject(project)
+		if req.params.str("statistics") == "true" {
+			p["statistics"] = s.projectStatistics(project)
+		}
+		s.json(w, http.StatusOK, p)
+	case "PUT":
+		if !s.validProjectSettings(w, req) {
+			return
+		}
+
+		updated := project.copy()
+		// Projects are moved through the transfer API, not updated.
+		updated.merge(req.params, "id", "namespace_id", "avatar")
+		updated["namespace_id"] = project["namespace_id"]
+		s.syncProjectFeatures(updated, req.params)
+		s.syncProjectTopics(updated, req.params)
+
+		switch avatar := req.params["avatar"].(type) {
+		case fakeObject:
+			updated["avatar_url"] = fmt.Sprintf("%s/uploads/-/system/project/avatar/%d/%s?%x",
+				s.URL, project.int("id"), avatar.str("filename"), sha1.Sum([]byte(avatar.str("content"))))
+		case string:
+			if avatar == "" {
+				updated["avatar_url"] = nil
+			}
+		}
+
+		// GitLab silently ignores a default branch which does not exist.
+		if v, ok := req.params["default_branch"]; ok && !fakeContains(s.branches[project.int("id")], fmt.Sprint(v)) {
+			updated["default_branch"] = project["default_branch"]
+		}
+
+		if !s.validateProject(w, updated, http.StatusBadRequest) {
+			return
+		}
+		project.merge(updated)
+		s.json(w, http.StatusOK, s.renderProject(project))
+	case "DELETE":
+		s.deleteProject(project)
+		s.fail(w, http.StatusAccepted, "message", "202 Accepted")
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+	}
+}
+
+func (s *fakeGitLab) renderBranch(project fakeObject, branch string) fakeObject {
+	return fakeObject{
+		"name":      branch,
+		"default":   project.str("default_branch") == branch,
+		"protected": false,
+		"merged":    false,
+		"commit":    fakeObject{"id": fmt.Sprintf("%040x", len(branch))},
+	}
+}
+
+func (s *fakeGitLab) serveBranches(w http.ResponseWriter, req *fakeRequest, project fakeObject) {
+	id := project.int("id")
+
+	if req.route("GET", "projects", "*", "repository", "branches") {
+		branches := []fakeObject{}
+		for _, branch := range s.branches[id] {
+			branches = append(branches, s.renderBranch(project, branch))
+		}
+		s.page(w, req, branches)
+		return
+	}
+
+	if req.route("POST", "projects", "*", "repository", "branches") {
+		if s.missing(w, req, "branch", "ref") {
+			return
+		}
+		if !fakeContains(s.branches[id], req.params.str("ref")) {
+			s.fail(w, http.StatusBadRequest, "message", "Invalid reference name: "+req.params.str("ref"))
+			return
+		}
+		if fakeContains(s.branches[id], req.params.str("branch")) {
+			s.fail(w, http.StatusBadRequest, "message", "Branch already exists")
+			return
+		}
+		s.branches[id] = append(s.branches[id], req.params.str("branch"))
+		s.json(w, http.StatusCreated, s.renderBranch(project, req.params.str("branch")))
+		return
+	}
+
+	if req.route("GET", "projects", "*", "repository", "branches", "*") {
+		if !fakeContains(s.branches[id], req.path[4]) {
+			s.notFound(w, "Branch")
+			return
+		}
+		s.json(w, http.StatusOK, s.renderBranch(project, req.path[4]))
+		return
+	}
+
+	s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+}
+
+func (s *fakeGitLab) serveProjectFork(w http.ResponseWriter, req *fakeRequest, source fakeObject) {
+	if !req.route("POST", "projects", "*", "fork") {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+
+	fork := source.copy()
+	fork["namespace_id"] = s.userNamespace(req.actor).int("id")
+	if v, ok := req.params["namespace"]; ok {
+		ns := s.findNamespace(fmt.Sprint(v))
+		if ns == nil {
+			s.notFound(w, "Target Namespace")
+			return
+		}
+		fork["namespace_id"] = ns.int("id")
+	}
+	if v := req.params.str("name"); v != "" {
+		fork["name"] = v
+	}
+	if v := req.params.str("path"); v != "" {
+		fork["path"] = v
+	}
+	fork["id"] = 0
+	if !s.validateProject(w, fork, http.StatusConflict) {
+		return
+	}
+
+	fork["id"] = s.nextID("projects")
+	fork["forks_count"] = 0
+	fork["star_count"] = 0
+	fork["created_at"] = s.now()
+	fork["default_branch"] = nil
+	s.projects[fork.int("id")] = fork
+	s.forks[fork.int("id")] = source.int("id")
+	source["forks_count"] = source.int("forks_count") + 1
+	s.startImport(fork, "")
+
+	s.json(w, http.StatusCreated, s.renderProject(fork))
+}
+
+// findNamespace finds a namespace by ID or full path.
+func (s *fakeGitLab) findNamespace(id string) fakeObject {
+	for _, nsID := range append(s.sortedIDs(s.groups), s.sortedIDs(s.namespaces)...) {
+		ns := s.namespace(nsID)
+		if id == strconv.Itoa(nsID) || strings.EqualFold(id, ns.str("full_path")) {
+			return ns
+		}
+	}
+	return nil
+}
+
+func (s *fakeGitLab) serveProjectTransfer(w http.ResponseWriter, req *fakeRequest, project fakeObject) {
+	if !req.route("PUT", "projects", "*", "transfer") {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	if s.missing(w, req, "namespace") {
+		return
+	}
+
+	ns := s.findNamespace(fmt.Sprint(req.params["namespace"]))
+	if ns == nil {
+		s.notFound(w, "Namespace")
+		return
+	}
+
+	for id, other := range s.projects {
+		if id != project.int("id") && other.int("namespace_id") == ns.int("id") && strings.EqualFold(other.str("path"), project.str("path")) {
+			s.fail(w, http.StatusBadRequest, "message", "Failed to transfer project: Project with same name or path in target namespace already exists")
+			return
+		}
+	}
+
+	project["namespace_id"] = ns.int("id")
+	s.json(w, http.StatusOK, s.renderProject(project))
+}
+
+func (s *fakeGitLab) deleteProject(project fakeObject) {
+	id := project.int("id")
+	for hookID, hook := range s.hooks {
+		if hook.int("project_id") == id {
+			delete(s.hooks, hookID)
+		}
+	}
+	delete(s.deployKeys, id)
+	delete(s.labels, id)
+	delete(s.branches, id)
+	delete(s.imports, id)
+	delete(s.forks, id)
+	delete(s.projects, id)
+}
+
+func fakeContains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fakeGitLab) serveHooks(w http.ResponseWriter, req *fakeRequest, project fakeObject) {
+	if req.route("GET", "projects", "*", "hooks") {
+		var hooks []fakeObject
+		for _, hook := range s.sortedObjects(s.hooks) {
+			if hook.int("project_id") == project.int("id") {
+				hooks = append(hooks, s.renderHook(hook))
+			}
+		}
+		s.page(w, req, hooks)
+		return
+	}
+
+	if req.route("POST", "projects", "*", "hooks") {
+		if s.missing(w, req, "url") {
+			return
+		}
+		hook := fakeObject{
+			"push_events":             true,
+			"issues_events":           false,
+			"merge_requests_events":   false,
+			"tag_push_events":         false,
+			"note_events":             false,
+			"job_events":              false,
+			"pipeline_events":         false,
+			"wiki_page_events":        false,
+			"enable_ssl_verification": true,
+			"created_at":              s.now(),
+		}
+		hook.merge(req.params)
+		hook["id"] = s.nextID("hooks")
+		hook["project_id"] = project.int("id")
+		s.hooks[hook.int("id")] = hook
+		s.json(w, http.StatusCreated, s.renderHook(hook))
+		return
+	}
+
+	if len(req.path) != 4 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	hook := s.lookup(s.hooks, req.path[3], nil)
+	if hook == nil || hook.int("project_id") != project.int("id") {
+		s.fail(w, http.StatusNotFound, "message", "404 Not found")
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		s.json(w, http.StatusOK, s.renderHook(hook))
+	case "PUT":
+		if s.missing(w, req, "url") {
+			return
+		}
+		hook.merge(req.params, "id", "project_id")
+		s.json(w, http.StatusOK, s.renderHook(hook))
+	case "DELETE":
+		delete(s.hooks, hook.int("id"))
+		s.json(w, http.StatusOK, s.renderHook(hook))
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+	}
+}
+
+func (s *fakeGitLab) renderHook(hook fakeObject) fakeObject {
+	h := hook.copy()
+	delete(h, "token")
+	return h
+}
+
+// deployKeyPattern matches the public key formats accepted by GitLab.
+var deployKeyPattern = regexp.MustCompile(`^(ssh-(rsa|dss|ed25519)|ecdsa-sha2-nistp(256|384|521)) [A-Za-z0-9+/=]+( .*)?$`)
+
+func (s *fakeGitLab) serveDeployKeys(w http.ResponseWriter, req *fakeRequest, project fakeObject) {
+	id := project.int("id")
+
+	if req.route("GET", "projects", "*", "deploy_keys") {
+		s.page(w, req, s.deployKeys[id])
+		return
+	}
+
+	if req.route("POST", "projects", "*", "deploy_keys") {
+		if s.missing(w, req, "key", "title") {
+			return
+		}
+		key := strings.TrimSpace(req.params.str("key"))
+		if !deployKeyPattern.MatchString(key) {
+			s.fail(w, http.StatusBadRequest, "message", fakeObject{
+				"key":         []string{"is invalid"},
+				"fingerprint": []string{"cannot be generated"},
+			})
+			return
+		}
+		for _, other := range s.deployKeys[id] {
+			if other.str("key") == key {
+				s.fail(w, http.StatusBadRequest, "message", fakeObject{
+					"deploy_key.fingerprint": []string{"has already been taken"},
+				})
+				return
+			}
+		}
+
+		deployKey := fakeObject{
+			"id":         s.nextID("deploy_keys"),
+			"title":      req.params.str("title"),
+			"key":        key,
+			"can_push":   req.params["can_push"] == true,
+			"created_at": s.now(),
+		}
+		s.deployKeys[id] = append(s.deployKeys[id], deployKey)
+		s.json(w, http.StatusCreated, deployKey)
+		return
+	}
+
+	if len(req.path) != 4 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+	for i, deployKey := range s.deployKeys[id] {
+		if strconv.Itoa(deployKey.int("id")) != req.path[3] {
+			continue
+		}
+
+		switch req.Method {
+		case "GET":
+			s.json(w, http.StatusOK, deployKey)
+		case "DELETE":
+			s.deployKeys[id] = append(s.deployKeys[id][:i], s.deployKeys[id][i+1:]...)
+			s.json(w, http.StatusNoContent, nil)
+		default:
+			s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+		}
+		return
+	}
+	s.fail(w, http.StatusNotFound, "message", "404 Not found")
+}
+
+// labelColorPattern matches the colors accepted by GitLab.
+var labelColorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}){1,2}$`)
+
+func (s *fakeGitLab) serveLabels(w http.ResponseWriter, req *fakeRequest, project fakeObject) {
+	id := project.int("id")
+	if len(req.path) != 3 {
+		s.fail(w, http.StatusNotFound, "error", "404 Not Found")
+		return
+	}
+
+	if req.Method == "GET" {
+		labels := append([]fakeObject(nil), s.labels[id]...)
+		sort.Sort(fakeLabelsByName(labels))
+		s.page(w, req, labels)
+		return
+	}
+
+	if s.missing(w, req, "name") {
+		return
+	}
+	if v := req.params.str("color"); v != "" && !labelColorPattern.MatchString(v) {
+		s.fail(w, http.StatusBadRequest, "message", fakeObject{"color": []string{"must be a valid color code"}})
+		return
+	}
+
+	var label fakeObject
+	var index int
+	for i, l := range s.labels[id] {
+		if l.str("name") == req.params.str("name") {
+			label, index = l, i
+		}
+	}
+
+	switch req.Method {
+	case "POST":
+		if s.missing(w, req, "color") {
+			return
+		}
+		if label != nil {
+			s.fail(w, http.StatusConflict, "message", "Label already exists")
+			return
+		}
+		label = fakeObject{
+			"id":                        s.nextID("labels"),
+			"description":               nil,
+			"open_issues_count":         0,
+			"closed_issues_count":       0,
+			"open_merge_requests_count": 0,
+			"priority":                  nil,
+			"subscribed":                false,
+		}
+		label.merge(req.params, "id")
+		s.labels[id] = append(s.labels[id], label)
+		s.json(w, http.StatusCreated, label)
+	case "PUT":
+		if label == nil {
+			s.notFound(w, "Label")
+			return
+		}
+		_, newName := req.params["new_name"]
+		_, color := req.params["color"]
+		_, description := req.params["description"]
+		if !newName && !color && !description {
+			s.fail(w, http.StatusBadRequest, "error", "new_name, color, description are missing, at least one parameter must be provided")
+			return
+		}
+		label.merge(req.params, "id", "name", "new_name")
+		if newName {
+			label["name"] = req.params.str("new_name")
+		}
+		s.json(w, http.StatusOK, label)
+	case "DELETE":
+		if label == nil {
+			s.notFound(w, "Label")
+			return
+		}
+		s.labels[id] = append(s.labels[id][:index], s.labels[id][index+1:]...)
+		s.json(w, http.StatusNoContent, nil)
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "error", "405 Method Not Allowed")
+	}
+}
+
+type fakeLabelsByName []fakeObject
+
+func (l fakeLabelsByName) Len() int           { return len(l) }
+func (l fakeLabelsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l fakeLabelsByName) Less(i, j int) bool { return l[i].str("name") < l[j].str("name") }
+
+func TestFakeGitLab_pagination(t *testing.T) {
+	server := newFakeGitLab()
+	defer server.Close()
+	client := server.client(t)
+
+	project, _, err := client.Projects.CreateProject(&gitlab.CreateProjectOptions{Name: gitlab.String("foo")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for i := 0; i < 150; i++ {
+		_, _, err := client.Labels.CreateLabel(project.PathWithNamespace, &gitlab.CreateLabelOptions{
+			Name:  gitlab.String(fmt.Sprintf("label-%03d", i)),
+			Color: gitlab.String("#ffcc00"),
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	firstPage, resp, err := client.Labels.ListLabels(project.PathWithNamespace)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(firstPage) != 20 || resp.NextPage != 2 || resp.LastPage != 8 || resp.Header.Get("X-Total") != "150" {
+		t.Fatalf("got %d labels, next page %d and last page %d; want 20, 2 and 8", len(firstPage), resp.NextPage, resp.LastPage)
+	}
+
+	labels, _, err := listLabels(client, fmt.Sprintf("%d", project.ID))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(labels) != 150 || labels[149].Name != "label-149" {
+		t.Fatalf("got %d labels; want 150", len(labels))
+	}
+}
+
+func TestFakeGitLab_errors(t *testing.T) {
+	server := newFakeGitLab()
+	defer server.Close()
+	client := server.client(t)
+
+	_, _, err := client.Projects.GetProject("root/missing")
+	if !isNotFound(err) || !strings.Contains(apiError(err, "reading project").Error(), "404 Project Not Found") {
+		t.Fatalf("got error %v; want a 404", err)
+	}
+
+	options := &gitlab.CreateProjectOptions{Name: gitlab.String("foo")}
+	if _, _, err := client.Projects.CreateProject(options); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, _, err = client.Projects.CreateProject(options)
+	if want := "name has already been taken; path has already been taken"; err == nil || !strings.Contains(apiError(err, "creating project").Error(), want) {
+		t.Fatalf("got error %v; want %q", err, want)
+	}
+
+	label := &gitlab.CreateLabelOptions{Name: gitlab.String("bug"), Color: gitlab.String("#ff0000")}
+	if _, _, err := client.Labels.CreateLabel("root/foo", label); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, _, err := client.Labels.CreateLabel("root/foo", label); !isConflict(err) {
+		t.Fatalf("got error %v; want a conflict", err)
+	}
+
+	unauthorized := gitlab.NewClient(nil, "wrong")
+	unauthorized.SetBaseURL(server.URL + "/api/v4/")
+	if _, _, err := unauthorized.Users.CurrentUser(); apiStatusCode(err) != http.StatusUnauthorized {
+		t.Fatalf("got error %v; want a 401", err)
+	}
+}
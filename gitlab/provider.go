@@ -3,8 +3,10 @@ package gitlab
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -16,10 +18,24 @@ func Provider() terraform.ResourceProvider {
 		Schema: map[string]*schema.Schema{
 			"token": {
 				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("GITLAB_TOKEN", nil),
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("GITLAB_TOKEN", ""),
 				Description: descriptions["token"],
 			},
+			"token_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("GITLAB_TOKEN_TYPE", "private"),
+				Description:  descriptions["token_type"],
+				ValidateFunc: validation.StringInSlice([]string{"private", "oauth", "job"}, false),
+			},
+			"token_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GITLAB_TOKEN_COMMAND", ""),
+				Description: descriptions["token_command"],
+			},
 			"base_url": {
 				Type:         schema.TypeString,
 				Optional:     true,
@@ -33,12 +49,72 @@ func Provider() terraform.ResourceProvider {
 				Default:     "",
 				Description: descriptions["cacert_file"],
 			},
+			"client_cert": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GITLAB_CLIENT_CERT", ""),
+				Description: descriptions["client_cert"],
+			},
+			"client_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("GITLAB_CLIENT_KEY", ""),
+				Description: descriptions["client_key"],
+			},
 			"insecure": {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     false,
 				Description: descriptions["insecure"],
 			},
+			"skip_credentials_validation": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["skip_credentials_validation"],
+			},
+			"sudo": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GITLAB_SUDO", ""),
+				Description: descriptions["sudo"],
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      3,
+				Description:  descriptions["max_retries"],
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"retry_wait_min": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				Description:  descriptions["retry_wait_min"],
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"retry_wait_max": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      30,
+				Description:  descriptions["retry_wait_max"],
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				Description:  descriptions["max_concurrent_requests"],
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Default:      0.0,
+				Description:  descriptions["requests_per_second"],
+				ValidateFunc: validateNonNegativeFloat,
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"gitlab_group":        resourceGitlabGroup(),
@@ -57,22 +133,59 @@ var descriptions map[string]string
 
 func init() {
 	descriptions = map[string]string{
-		"token": "The OAuth token used to connect to GitLab.",
+		"token": "The token used to connect to GitLab.",
+
+		"token_type": "The kind of token given in token: private, oauth or job",
+
+		"token_command": "A command printing the token to use on stdout; takes precedence over token",
 
 		"base_url": "The GitLab Base API URL",
 
 		"cacert_file": "A file containing the ca certificate to use in case ssl certificate is not from a standard chain",
 
+		"client_cert": "File path or PEM content of the client certificate used for mutual TLS authentication",
+
+		"client_key": "File path or PEM content of the private key matching client_cert",
+
 		"insecure": "Disable SSL verification of API calls",
+
+		"skip_credentials_validation": "Skip looking up the authenticated user when configuring the provider",
+
+		"sudo": "Username or ID of the user to act on behalf of by default; requires an administrator token",
+
+		"max_retries": "Maximum number of times a rate limited or failed idempotent request is retried",
+
+		"retry_wait_min": "Minimum time in seconds to wait before retrying a request",
+
+		"retry_wait_max": "Maximum time in seconds to wait before retrying a request",
+
+		"max_concurrent_requests": "Maximum number of requests sent to GitLab at the same time; 0 means unlimited",
+
+		"requests_per_second": "Maximum number of requests sent to GitLab per second; 0 means unlimited",
 	}
 }
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 	config := Config{
-		Token:      d.Get("token").(string),
-		BaseURL:    d.Get("base_url").(string),
-		CACertFile: d.Get("cacert_file").(string),
-		Insecure:   d.Get("insecure").(bool),
+		Token:        d.Get("token").(string),
+		TokenType:    d.Get("token_type").(string),
+		TokenCommand: d.Get("token_command").(string),
+		BaseURL:      d.Get("base_url").(string),
+		CACertFile:   d.Get("cacert_file").(string),
+		ClientCert:   d.Get("client_cert").(string),
+		ClientKey:    d.Get("client_key").(string),
+		Insecure:     d.Get("insecure").(bool),
+
+		MaxRetries:   d.Get("max_retries").(int),
+		RetryWaitMin: time.Duration(d.Get("retry_wait_min").(int)) * time.Second,
+		RetryWaitMax: time.Duration(d.Get("retry_wait_max").(int)) * time.Second,
+
+		MaxConcurrentRequests: d.Get("max_concurrent_requests").(int),
+		RequestsPerSecond:     d.Get("requests_per_second").(float64),
+
+		SkipCredentialsValidation: d.Get("skip_credentials_validation").(bool),
+
+		Sudo: d.Get("sudo").(string),
 	}
 
 	return config.Client()
@@ -85,3 +198,10 @@ func validateApiURLVersion(value interface{}, key string) (ws []string, es []err
 	}
 	return
 }
+
+func validateNonNegativeFloat(value interface{}, key string) (ws []string, es []error) {
+	if v := value.(float64); v < 0 {
+		es = append(es, fmt.Errorf("%s must not be negative, got %v", key, v))
+	}
+	return
+}
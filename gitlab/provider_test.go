@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/schema"
@@ -11,6 +12,11 @@ import (
 var testAccProviders map[string]terraform.ResourceProvider
 var testAccProvider *schema.Provider
 
+// testAccFakeGitLab is the fake GitLab acceptance tests run against when no
+// real instance is configured.
+var testAccFakeGitLab *fakeGitLab
+var testAccFakeGitLabOnce sync.Once
+
 func init() {
 	testAccProvider = Provider().(*schema.Provider)
 	testAccProviders = map[string]terraform.ResourceProvider{
@@ -28,8 +34,26 @@ func TestProvider_impl(t *testing.T) {
 	var _ terraform.ResourceProvider = Provider()
 }
 
+// testAccPreCheck points the provider at an in-process fake GitLab unless
+// GITLAB_TOKEN is set, in which case tests run against the instance
+// configured through the GITLAB_* environment variables.
 func testAccPreCheck(t *testing.T) {
-	if v := os.Getenv("GITLAB_TOKEN"); v == "" {
-		t.Fatal("GITLAB_TOKEN must be set for acceptance tests")
+	testAccFakeGitLabOnce.Do(func() {
+		if os.Getenv("GITLAB_TOKEN") != "" {
+			return
+		}
+
+		testAccFakeGitLab = newFakeGitLab()
+		os.Setenv("GITLAB_TOKEN", testAccFakeGitLab.token)
+		os.Setenv("GITLAB_BASE_URL", testAccFakeGitLab.URL+"/api/v4/")
+	})
+}
+
+// testAccPreCheckPremium skips tests of GitLab Premium features when running
+// against a real instance, unless GITLAB_PREMIUM is set.
+func testAccPreCheckPremium(t *testing.T) {
+	testAccPreCheck(t)
+	if testAccFakeGitLab == nil && os.Getenv("GITLAB_PREMIUM") == "" {
+		t.Skip("set GITLAB_PREMIUM to run tests of GitLab Premium features against a real instance")
 	}
 }
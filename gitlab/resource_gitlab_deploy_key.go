@@ -14,13 +14,26 @@ func resourceGitlabDeployKey() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceGitlabDeployKeyCreate,
 		Read:   resourceGitlabDeployKeyRead,
+		Update: resourceGitlabDeployKeyUpdate,
 		Delete: resourceGitlabDeployKeyDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceGitlabDeployKeyImportState,
+		},
 
 		Schema: map[string]*schema.Schema{
-			"project": {
+			"project": projectSchema(),
+			"project_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"project_path": {
 				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Computed: true,
+			},
+			"project_paths": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 			"title": {
 				Type:     schema.TypeString,
@@ -41,13 +54,21 @@ func resourceGitlabDeployKey() *schema.Resource {
 				Default:  false,
 				ForceNew: true,
 			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
 
 func resourceGitlabDeployKeyCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	options := &gitlab.AddDeployKeyOptions{
 		Title:   gitlab.String(d.Get("title").(string)),
 		Key:     gitlab.String(strings.TrimSpace(d.Get("key").(string))),
@@ -56,9 +77,9 @@ func resourceGitlabDeployKeyCreate(d *schema.ResourceData, meta interface{}) err
 
 	log.Printf("[DEBUG] create gitlab deployment key %s", *options.Title)
 
-	deployKey, _, err := client.DeployKeys.AddDeployKey(project, options)
+	deployKey, _, err := client.DeployKeys.AddDeployKey(project, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "creating deploy key %q in project %s", *options.Title, project)
 	}
 
 	d.SetId(fmt.Sprintf("%d", deployKey.ID))
@@ -67,23 +88,33 @@ func resourceGitlabDeployKeyCreate(d *schema.ResourceData, meta interface{}) err
 }
 
 func resourceGitlabDeployKeyRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	deployKeyID, err := strconv.Atoi(d.Id())
 	if err != nil {
 		return err
 	}
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] removing deploy key %d from state because its project no longer exists in gitlab", deployKeyID)
+			d.SetId("")
+			return nil
+		}
+
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	log.Printf("[DEBUG] read gitlab deploy key %s/%d", project, deployKeyID)
 
-	deployKey, response, err := client.DeployKeys.GetDeployKey(project, deployKeyID)
+	deployKey, _, err := client.DeployKeys.GetDeployKey(project, deployKeyID, sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing deploy key %d from state because it no longer exists in gitlab", deployKeyID)
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading deploy key %d of project %s", deployKeyID, project)
 	}
 
 	d.Set("title", deployKey.Title)
@@ -92,20 +123,40 @@ func resourceGitlabDeployKeyRead(d *schema.ResourceData, meta interface{}) error
 	return nil
 }
 
+func resourceGitlabDeployKeyUpdate(d *schema.ResourceData, meta interface{}) error {
+	// Every other argument forces a new resource, so only sudo can change
+	// here; it is picked up by the next API calls.
+	return resourceGitlabDeployKeyRead(d, meta)
+}
+
+func resourceGitlabDeployKeyImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	project, id, err := parseProjectImportID(d.Id(), "key_id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("unexpected format of ID (%s), key_id must be a number", d.Id())
+	}
+
+	d.Set("project", project)
+	d.SetId(id)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGitlabDeployKeyDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	project := projectOf(d)
 	deployKeyID, err := strconv.Atoi(d.Id())
 	if err != nil {
 		return err
 	}
 	log.Printf("[DEBUG] Delete gitlab deploy key %s", d.Id())
 
-	response, err := client.DeployKeys.DeleteDeployKey(project, deployKeyID)
-
-	// HTTP 204 is success with no body
-	if response.StatusCode == 204 {
-		return nil
+	_, err = client.DeployKeys.DeleteDeployKey(project, deployKeyID, sudo...)
+	if err != nil && !isNotFound(err) {
+		return apiError(err, "deleting deploy key %d of project %s", deployKeyID, project)
 	}
-	return err
+	return nil
 }
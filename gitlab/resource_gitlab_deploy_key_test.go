@@ -73,6 +73,77 @@ func TestAccGitlabDeployKey_suppressfunc(t *testing.T) {
 	})
 }
 
+func TestAccGitlabDeployKey_import(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabDeployKeyDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabDeployKeyImportConfig(rInt),
+			},
+			{
+				ResourceName:        "gitlab_deploy_key.foo",
+				ImportState:         true,
+				ImportStateIdPrefix: fmt.Sprintf("foo-group-%d/foo-%d:", rInt, rInt),
+				ImportStateVerify:   true,
+			},
+		},
+	})
+}
+
+func TestAccGitlabDeployKey_projectRenamed(t *testing.T) {
+	var project gitlab.Project
+	var deployKey gitlab.DeployKey
+	var deployKeyID int
+	rInt := acctest.RandInt()
+	path := fmt.Sprintf("foo-group-%d/foo-%d", rInt, rInt)
+	renamed := fmt.Sprintf("foo-group-%d/foo-renamed-%d", rInt, rInt)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabDeployKeyDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabDeployKeyProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabDeployKeyExists("gitlab_deploy_key.foo", &deployKey),
+					func(*terraform.State) error {
+						deployKeyID = deployKey.ID
+						return nil
+					},
+				),
+			},
+			// Rename the project outside of Terraform, keeping the old path in
+			// the configuration
+			{
+				PreConfig: testAccRenameGitlabProject(t, &project, fmt.Sprintf("foo-renamed-%d", rInt)),
+				Config:    testAccGitlabDeployKeyProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabDeployKeyExists("gitlab_deploy_key.foo", &deployKey),
+					func(*terraform.State) error {
+						if deployKey.ID != deployKeyID {
+							return fmt.Errorf("got deploy key %d; want deploy key %d to be kept", deployKey.ID, deployKeyID)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttrPair("gitlab_deploy_key.foo", "project_id", "gitlab_project.foo", "id"),
+					resource.TestCheckResourceAttr("gitlab_deploy_key.foo", "project", path),
+					resource.TestCheckResourceAttr("gitlab_deploy_key.foo", "project_path", renamed),
+				),
+			},
+			{
+				Config:   testAccGitlabDeployKeyProjectConfig(rInt, path),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
 func testAccCheckGitlabDeployKeyExists(n string, deployKey *gitlab.DeployKey) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -84,11 +155,11 @@ func testAccCheckGitlabDeployKeyExists(n string, deployKey *gitlab.DeployKey) re
 		if err != nil {
 			return err
 		}
-		repoName := rs.Primary.Attributes["project"]
+		repoName := rs.Primary.Attributes["project_id"]
 		if repoName == "" {
 			return fmt.Errorf("No project ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
 		gotDeployKey, _, err := conn.DeployKeys.GetDeployKey(repoName, deployKeyID)
 		if err != nil {
@@ -124,22 +195,22 @@ func testAccCheckGitlabDeployKeyAttributes(deployKey *gitlab.DeployKey, want *te
 }
 
 func testAccCheckGitlabDeployKeyDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_project" {
 			continue
 		}
 		deployKeyID, err := strconv.Atoi(rs.Primary.ID)
-		project := rs.Primary.Attributes["project"]
+		project := rs.Primary.Attributes["project_id"]
 
-		gotDeployKey, resp, err := conn.DeployKeys.GetDeployKey(project, deployKeyID)
+		gotDeployKey, _, err := conn.DeployKeys.GetDeployKey(project, deployKeyID)
 		if err == nil {
 			if gotDeployKey != nil && fmt.Sprintf("%d", gotDeployKey.ID) == rs.Primary.ID {
 				return fmt.Errorf("Deploy key still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -166,6 +237,62 @@ resource "gitlab_deploy_key" "foo" {
   `, rInt, rInt, suffix)
 }
 
+func testAccGitlabDeployKeyImportConfig(rInt int) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-group-%d"
+  path = "foo-group-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  namespace_id = "${gitlab_group.foo.id}"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_deploy_key" "foo" {
+  project = "${gitlab_project.foo.path_with_namespace}"
+  title = "deployKey-%d"
+  key = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCj13ozEBZ0s4el4k6mYqoyIKKKMh9hHY0sAYqSPXs2zGuVFZss1P8TPuwmdXVjHR7TiRXwC49zDrkyWJgiufggYJ1VilOohcMOODwZEJz+E5q4GCfHuh90UEh0nl8B2R0Uoy0LPeg93uZzy0hlHApsxRf/XZJz/1ytkZvCtxdllxfImCVxJReMeRVEqFCTCvy3YuJn0bce7ulcTFRvtgWOpQsr6GDK8YkcCCv2eZthVlrEwy6DEpAKTRiRLGgUj4dPO0MmO4cE2qD4ualY01PhNORJ8Q++I+EtkGt/VALkecwFuBkl18/gy+yxNJHpKc/8WVVinDeFrd/HhiY9yU0d richardc@tamborine.example.1"
+  can_push = true
+}
+  `, rInt, rInt, rInt, rInt)
+}
+
+func testAccGitlabDeployKeyProjectConfig(rInt int, project string) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-group-%d"
+  path = "foo-group-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  namespace_id = "${gitlab_group.foo.id}"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_deploy_key" "foo" {
+  project = "%s"
+  title = "deployKey-%d"
+  key = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCj13ozEBZ0s4el4k6mYqoyIKKKMh9hHY0sAYqSPXs2zGuVFZss1P8TPuwmdXVjHR7TiRXwC49zDrkyWJgiufggYJ1VilOohcMOODwZEJz+E5q4GCfHuh90UEh0nl8B2R0Uoy0LPeg93uZzy0hlHApsxRf/XZJz/1ytkZvCtxdllxfImCVxJReMeRVEqFCTCvy3YuJn0bce7ulcTFRvtgWOpQsr6GDK8YkcCCv2eZthVlrEwy6DEpAKTRiRLGgUj4dPO0MmO4cE2qD4ualY01PhNORJ8Q++I+EtkGt/VALkecwFuBkl18/gy+yxNJHpKc/8WVVinDeFrd/HhiY9yU0d richardc@tamborine.example.1"
+
+  # The project may be given by a literal path
+  depends_on = ["gitlab_project.foo"]
+}
+  `, rInt, rInt, rInt, project, rInt)
+}
+
 func testAccGitlabDeployKeyUpdateConfig(rInt int) string {
 	return fmt.Sprintf(`
 resource "gitlab_project" "foo" {
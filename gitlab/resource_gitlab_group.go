@@ -3,7 +3,9 @@ package gitlab
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	gitlab "github.com/xanzy/go-gitlab"
@@ -16,7 +18,12 @@ func resourceGitlabGroup() *schema.Resource {
 		Update: resourceGitlabGroupUpdate,
 		Delete: resourceGitlabGroupDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceGitlabGroupImportState,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -51,15 +58,32 @@ func resourceGitlabGroup() *schema.Resource {
 			"parent_id": {
 				Type:     schema.TypeInt,
 				Optional: true,
-				ForceNew: true,
 				Default:  0,
 			},
+			"full_path": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"full_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
 
 func resourceGitlabGroupCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	options := &gitlab.CreateGroupOptions{
 		Name:                 gitlab.String(d.Get("name").(string)),
 		LFSEnabled:           gitlab.Bool(d.Get("lfs_enabled").(bool)),
@@ -82,11 +106,11 @@ func resourceGitlabGroupCreate(d *schema.ResourceData, meta interface{}) error {
 		options.ParentID = gitlab.Int(v.(int))
 	}
 
-	log.Printf("[DEBUG] create gitlab group %q", options.Name)
+	log.Printf("[DEBUG] create gitlab group %q", *options.Name)
 
-	group, _, err := client.Groups.CreateGroup(options)
+	group, _, err := client.Groups.CreateGroup(options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "creating group %q", *options.Name)
 	}
 
 	d.SetId(fmt.Sprintf("%d", group.ID))
@@ -95,18 +119,19 @@ func resourceGitlabGroupCreate(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabGroupRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	log.Printf("[DEBUG] read gitlab group %s", d.Id())
 
-	group, response, err := client.Groups.GetGroup(d.Id())
+	group, _, err := client.Groups.GetGroup(d.Id(), sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing group %s from state because it no longer exists in gitlab", d.Id())
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading group %s", d.Id())
 	}
 
 	d.SetId(fmt.Sprintf("%d", group.ID))
@@ -117,12 +142,23 @@ func resourceGitlabGroupRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("request_access_enabled", group.RequestAccessEnabled)
 	d.Set("visibility_level", group.Visibility)
 	d.Set("parent_id", group.ParentID)
+	// The full path and name follow the parent, so they change whenever the
+	// group is moved, including by someone else.
+	d.Set("full_path", group.FullPath)
+	d.Set("full_name", group.FullName)
 
 	return nil
 }
 
 func resourceGitlabGroupUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	if d.HasChange("parent_id") {
+		if err := resourceGitlabGroupTransfer(d, meta); err != nil {
+			return err
+		}
+	}
 
 	options := &gitlab.UpdateGroupOptions{}
 
@@ -154,18 +190,105 @@ func resourceGitlabGroupUpdate(d *schema.ResourceData, meta interface{}) error {
 
 	log.Printf("[DEBUG] update gitlab group %s", d.Id())
 
-	_, _, err := client.Groups.UpdateGroup(d.Id(), options)
+	_, _, err := client.Groups.UpdateGroup(d.Id(), options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "updating group %s", d.Id())
 	}
 
 	return resourceGitlabGroupRead(d, meta)
 }
 
+// resourceGitlabGroupTransfer moves the group, with its subgroups and
+// projects, under the group set in parent_id, or to the top level when
+// parent_id is 0, and waits until GitLab reports the new parent.
+func resourceGitlabGroupTransfer(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	parent := d.Get("parent_id").(int)
+
+	if err := meta.(*providerMeta).requireFeature("group_transfer", "Changing the parent_id of a gitlab_group"); err != nil {
+		return err
+	}
+
+	options := &transferGroupOptions{}
+	if parent != 0 {
+		options.GroupID = gitlab.Int(parent)
+	}
+
+	log.Printf("[DEBUG] transfer gitlab group %s to parent %d", d.Id(), parent)
+
+	_, _, err := transferGroup(client, d.Id(), options, sudo...)
+	if err != nil {
+		return apiError(err, "transferring group %s to parent %d", d.Id(), parent)
+	}
+
+	stateConf := &resource.StateChangeConf{
+		Pending: []string{"Transferring"},
+		Target:  []string{"Transferred"},
+		Refresh: func() (interface{}, string, error) {
+			group, _, err := client.Groups.GetGroup(d.Id(), sudo...)
+			if err != nil {
+				return nil, "Error", apiError(err, "reading group %s", d.Id())
+			}
+			if group.ParentID != parent {
+				return group, "Transferring", nil
+			}
+			return group, "Transferred", nil
+		},
+
+		Timeout:    d.Timeout(schema.TimeoutUpdate),
+		MinTimeout: 3 * time.Second,
+	}
+
+	if _, err := stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("error waiting for group %s to be transferred to parent %d: %s", d.Id(), parent, err)
+	}
+	return nil
+}
+
+func resourceGitlabGroupImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	// Groups can be imported by full path as well as by ID, but the state
+	// always records the numeric ID.
+	group, _, err := client.Groups.GetGroup(d.Id(), sudo...)
+	if err != nil {
+		return nil, apiError(err, "importing group %s", d.Id())
+	}
+	d.SetId(fmt.Sprintf("%d", group.ID))
+
+	// deletion_protection only exists in Terraform, so it is not read back
+	// from GitLab and starts out with its default.
+	d.Set("deletion_protection", false)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGitlabGroupDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	// Deleting a group deletes every project and subgroup in it.
+	if err := checkDeletionProtection(d, fmt.Sprintf("group %s", d.Id())); err != nil {
+		return err
+	}
 	log.Printf("[DEBUG] Delete gitlab group %s", d.Id())
 
-	_, err := client.Groups.DeleteGroup(d.Id())
-	return err
+	_, err := client.Groups.DeleteGroup(d.Id(), sudo...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return apiError(err, "deleting group %s", d.Id())
+	}
+
+	// Groups, like projects, are deleted in the background.
+	return waitForDeletion(fmt.Sprintf("group %s", d.Id()), d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		group, _, err := getGroup(client, d.Id(), sudo...)
+		if err != nil {
+			return false, err
+		}
+		return group.MarkedForDeletionOn != "", nil
+	})
 }
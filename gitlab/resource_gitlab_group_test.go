@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
@@ -78,6 +79,39 @@ func TestAccGitlabGroup_import(t *testing.T) {
 				ImportState:       true,
 				ImportStateVerify: true,
 			},
+			{
+				ResourceName:      "gitlab_group.foo",
+				ImportState:       true,
+				ImportStateId:     fmt.Sprintf("foo-path-%d", rInt),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccGitlabGroup_nestedImport(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabGroupDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabNestedGroupConfig(rInt),
+			},
+			{
+				ResourceName:      "gitlab_group.nested_foo",
+				ImportState:       true,
+				ImportStateId:     fmt.Sprintf("foo-path-%d/nfoo-path-%d", rInt, rInt),
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:  "gitlab_group.nested_foo",
+				ImportState:   true,
+				ImportStateId: fmt.Sprintf("foo-path-%d/missing-%d", rInt, rInt),
+				ExpectError:   regexp.MustCompile("404"),
+			},
 		},
 	})
 }
@@ -86,6 +120,7 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 	var group gitlab.Group
 	var group2 gitlab.Group
 	var nestedGroup gitlab.Group
+	var nestedGroupID int
 	rInt := acctest.RandInt()
 
 	resource.Test(t, resource.TestCase{
@@ -99,6 +134,7 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
 					testAccCheckGitlabGroupExists("gitlab_group.foo2", &group2),
 					testAccCheckGitlabGroupExists("gitlab_group.nested_foo", &nestedGroup),
+					testAccCheckGitlabGroupNotRecreated(&nestedGroup, &nestedGroupID),
 					testAccCheckGitlabGroupAttributes(&nestedGroup, &testAccGitlabGroupExpectedAttributes{
 						Name:        fmt.Sprintf("nfoo-name-%d", rInt),
 						Path:        fmt.Sprintf("nfoo-path-%d", rInt),
@@ -114,6 +150,7 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
 					testAccCheckGitlabGroupExists("gitlab_group.foo2", &group2),
 					testAccCheckGitlabGroupExists("gitlab_group.nested_foo", &nestedGroup),
+					testAccCheckGitlabGroupNotRecreated(&nestedGroup, &nestedGroupID),
 					testAccCheckGitlabGroupAttributes(&nestedGroup, &testAccGitlabGroupExpectedAttributes{
 						Name:        fmt.Sprintf("nfoo-name-%d", rInt),
 						Path:        fmt.Sprintf("nfoo-path-%d", rInt),
@@ -121,6 +158,7 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 						LFSEnabled:  true,
 						Parent:      &group2,
 					}),
+					resource.TestCheckResourceAttr("gitlab_group.nested_foo", "full_path", fmt.Sprintf("foo2-path-%d/nfoo-path-%d", rInt, rInt)),
 				),
 			},
 			{
@@ -129,12 +167,14 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
 					testAccCheckGitlabGroupExists("gitlab_group.foo2", &group2),
 					testAccCheckGitlabGroupExists("gitlab_group.nested_foo", &nestedGroup),
+					testAccCheckGitlabGroupNotRecreated(&nestedGroup, &nestedGroupID),
 					testAccCheckGitlabGroupAttributes(&nestedGroup, &testAccGitlabGroupExpectedAttributes{
 						Name:        fmt.Sprintf("nfoo-name-%d", rInt),
 						Path:        fmt.Sprintf("nfoo-path-%d", rInt),
 						Description: "Terraform acceptance tests - updated",
 						LFSEnabled:  true,
 					}),
+					resource.TestCheckResourceAttr("gitlab_group.nested_foo", "full_path", fmt.Sprintf("nfoo-path-%d", rInt)),
 				),
 			},
 			{
@@ -143,6 +183,7 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
 					testAccCheckGitlabGroupExists("gitlab_group.foo2", &group2),
 					testAccCheckGitlabGroupExists("gitlab_group.nested_foo", &nestedGroup),
+					testAccCheckGitlabGroupNotRecreated(&nestedGroup, &nestedGroupID),
 					testAccCheckGitlabGroupAttributes(&nestedGroup, &testAccGitlabGroupExpectedAttributes{
 						Name:        fmt.Sprintf("nfoo-name-%d", rInt),
 						Path:        fmt.Sprintf("nfoo-path-%d", rInt),
@@ -156,6 +197,55 @@ func TestAccGitlabGroup_nested(t *testing.T) {
 	})
 }
 
+func TestAccGitlabGroup_deletionProtection(t *testing.T) {
+	var group gitlab.Group
+	var groupID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabGroupDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabGroupDeletionProtectionConfig(rInt, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
+					testAccCheckGitlabGroupNotRecreated(&group, &groupID),
+				),
+			},
+			// Deleting the group fails while it is protected
+			{
+				Config:      testAccGitlabGroupDeletionProtectionConfig(rInt, true),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("is protected against deletion"),
+			},
+			// Lift the protection so that the group can be destroyed
+			{
+				Config: testAccGitlabGroupDeletionProtectionConfig(rInt, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabGroupExists("gitlab_group.foo", &group),
+					testAccCheckGitlabGroupNotRecreated(&group, &groupID),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckGitlabGroupNotRecreated checks group keeps the ID it had when
+// first checked, i.e. that it was updated in place.
+func testAccCheckGitlabGroupNotRecreated(group *gitlab.Group, id *int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if *id == 0 {
+			*id = group.ID
+		}
+		if group.ID != *id {
+			return fmt.Errorf("got group %d; want group %d to be updated in place", group.ID, *id)
+		}
+		return nil
+	}
+}
+
 func testAccCheckGitlabGroupExists(n string, group *gitlab.Group) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -167,7 +257,7 @@ func testAccCheckGitlabGroupExists(n string, group *gitlab.Group) resource.TestC
 		if groupID == "" {
 			return fmt.Errorf("No group ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
 		gotGroup, _, err := conn.Groups.GetGroup(groupID)
 		if err != nil {
@@ -224,20 +314,20 @@ func testAccCheckGitlabGroupAttributes(group *gitlab.Group, want *testAccGitlabG
 }
 
 func testAccCheckGitlabGroupDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_group" {
 			continue
 		}
 
-		group, resp, err := conn.Groups.GetGroup(rs.Primary.ID)
+		group, _, err := conn.Groups.GetGroup(rs.Primary.ID)
 		if err == nil {
 			if group != nil && fmt.Sprintf("%d", group.ID) == rs.Primary.ID {
 				return fmt.Errorf("Group still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -372,3 +462,18 @@ resource "gitlab_group" "nested_foo" {
 }
   `, rInt, rInt, rInt, rInt, rInt, rInt)
 }
+
+func testAccGitlabGroupDeletionProtectionConfig(rInt int, protected bool) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-name-%d"
+  path = "foo-path-%d"
+  description = "Terraform acceptance tests"
+  deletion_protection = %t
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+  `, rInt, rInt, protected)
+}
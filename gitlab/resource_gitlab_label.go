@@ -13,11 +13,24 @@ func resourceGitlabLabel() *schema.Resource {
 		Read:   resourceGitlabLabelRead,
 		Update: resourceGitlabLabelUpdate,
 		Delete: resourceGitlabLabelDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceGitlabLabelImportState,
+		},
 
 		Schema: map[string]*schema.Schema{
-			"project": {
+			"project": projectSchema(),
+			"project_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"project_path": {
 				Type:     schema.TypeString,
-				Required: true,
+				Computed: true,
+			},
+			"project_paths": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -32,13 +45,21 @@ func resourceGitlabLabel() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
 
 func resourceGitlabLabelCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	options := &gitlab.CreateLabelOptions{
 		Name:  gitlab.String(d.Get("name").(string)),
 		Color: gitlab.String(d.Get("color").(string)),
@@ -50,9 +71,9 @@ func resourceGitlabLabelCreate(d *schema.ResourceData, meta interface{}) error {
 
 	log.Printf("[DEBUG] create gitlab label %s", *options.Name)
 
-	label, _, err := client.Labels.CreateLabel(project, options)
+	label, _, err := client.Labels.CreateLabel(project, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "creating label %q in project %s", *options.Name, project)
 	}
 
 	d.SetId(label.Name)
@@ -61,20 +82,30 @@ func resourceGitlabLabelCreate(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabLabelRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	labelName := d.Id()
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] removing label %s from state because its project no longer exists in gitlab", labelName)
+			d.SetId("")
+			return nil
+		}
+
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	log.Printf("[DEBUG] read gitlab label %s/%s", project, labelName)
 
-	labels, response, err := client.Labels.ListLabels(project)
+	labels, _, err := listLabels(client, project, sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing label %s from state because it no longer exists in gitlab", labelName)
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading labels of project %s", project)
 	}
 	found := false
 	for _, label := range labels {
@@ -95,8 +126,9 @@ func resourceGitlabLabelRead(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabLabelUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	project := projectOf(d)
 	options := &gitlab.UpdateLabelOptions{
 		Name:  gitlab.String(d.Get("name").(string)),
 		Color: gitlab.String(d.Get("color").(string)),
@@ -108,22 +140,38 @@ func resourceGitlabLabelUpdate(d *schema.ResourceData, meta interface{}) error {
 
 	log.Printf("[DEBUG] update gitlab label %s", d.Id())
 
-	_, _, err := client.Labels.UpdateLabel(project, options)
+	_, _, err := client.Labels.UpdateLabel(project, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "updating label %q in project %s", d.Id(), project)
 	}
 
 	return resourceGitlabLabelRead(d, meta)
 }
 
+func resourceGitlabLabelImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	project, name, err := parseProjectImportID(d.Id(), "label_name")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("project", project)
+	d.SetId(name)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGitlabLabelDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	project := projectOf(d)
 	log.Printf("[DEBUG] Delete gitlab label %s", d.Id())
 	options := &gitlab.DeleteLabelOptions{
 		Name: gitlab.String(d.Id()),
 	}
 
-	_, err := client.Labels.DeleteLabel(project, options)
-	return err
+	_, err := client.Labels.DeleteLabel(project, options, sudo...)
+	if err != nil && !isNotFound(err) {
+		return apiError(err, "deleting label %q in project %s", d.Id(), project)
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
@@ -59,6 +60,33 @@ func TestAccGitlabLabel_basic(t *testing.T) {
 	})
 }
 
+func TestAccGitlabLabel_import(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabLabelDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabLabelImportConfig(rInt),
+			},
+			{
+				ResourceName:        "gitlab_label.fixme",
+				ImportState:         true,
+				ImportStateIdPrefix: fmt.Sprintf("foo-group-%d/foo-%d:", rInt, rInt),
+				ImportStateVerify:   true,
+			},
+			{
+				ResourceName:  "gitlab_label.fixme",
+				ImportState:   true,
+				ImportStateId: fmt.Sprintf("foo-group-%d/foo-%d", rInt, rInt),
+				ExpectError:   regexp.MustCompile("expected project:label_name"),
+			},
+		},
+	})
+}
+
 func testAccCheckGitlabLabelExists(n string, label *gitlab.Label) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -67,13 +95,13 @@ func testAccCheckGitlabLabelExists(n string, label *gitlab.Label) resource.TestC
 		}
 
 		labelName := rs.Primary.ID
-		repoName := rs.Primary.Attributes["project"]
+		repoName := rs.Primary.Attributes["project_id"]
 		if repoName == "" {
 			return fmt.Errorf("No project ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
-		labels, _, err := conn.Labels.ListLabels(repoName)
+		labels, _, err := listLabels(conn, repoName)
 		if err != nil {
 			return err
 		}
@@ -112,20 +140,20 @@ func testAccCheckGitlabLabelAttributes(label *gitlab.Label, want *testAccGitlabL
 }
 
 func testAccCheckGitlabLabelDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_project" {
 			continue
 		}
 
-		gotRepo, resp, err := conn.Projects.GetProject(rs.Primary.ID)
+		gotRepo, _, err := conn.Projects.GetProject(rs.Primary.ID)
 		if err == nil {
 			if gotRepo != nil && fmt.Sprintf("%d", gotRepo.ID) == rs.Primary.ID {
 				return fmt.Errorf("Repository still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -153,6 +181,33 @@ resource "gitlab_label" "fixme" {
 	`, rInt, rInt)
 }
 
+func testAccGitlabLabelImportConfig(rInt int) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-group-%d"
+  path = "foo-group-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  namespace_id = "${gitlab_group.foo.id}"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_label" "fixme" {
+  project = "${gitlab_project.foo.path_with_namespace}"
+  name = "FIXME-%d"
+  color = "#ffcc00"
+  description = "fix this test"
+}
+	`, rInt, rInt, rInt, rInt)
+}
+
 func testAccGitlabLabelUpdateConfig(rInt int) string {
 	return fmt.Sprintf(`
 resource "gitlab_project" "foo" {
@@ -1,8 +1,12 @@
 package gitlab
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -18,7 +22,13 @@ func resourceGitlabProject() *schema.Resource {
 		Update: resourceGitlabProjectUpdate,
 		Delete: resourceGitlabProjectDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceGitlabProjectImportState,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -39,7 +49,6 @@ func resourceGitlabProject() *schema.Resource {
 			"namespace_id": {
 				Type:     schema.TypeInt,
 				Optional: true,
-				ForceNew: true,
 				Computed: true,
 			},
 			"description": {
@@ -49,6 +58,23 @@ func resourceGitlabProject() *schema.Resource {
 			"default_branch": {
 				Type:     schema.TypeString,
 				Optional: true,
+				Computed: true,
+			},
+			// Topics set in GitLab are kept unless the configuration sets
+			// them.
+			"topics": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			// The state keeps a hash of the avatar file rather than its path,
+			// so that a new image at the same path is uploaded again.
+			"avatar": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				StateFunc: resourceGitlabProjectAvatarHash,
 			},
 			"issues_enabled": {
 				Type:     schema.TypeBool,
@@ -76,6 +102,209 @@ func resourceGitlabProject() *schema.Resource {
 				ValidateFunc: validation.StringInSlice([]string{"private", "internal", "public"}, true),
 				Default:      "private",
 			},
+			// GitLab always reports the container registry and LFS as
+			// disabled when the instance disables them, so these keep their
+			// current value unless set.
+			"container_registry_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"lfs_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"request_access_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+
+			// Access levels supersede the *_enabled booleans, which are
+			// ignored while they are set. They are only read back once set,
+			// so that projects keep being managed with the booleans alone.
+			"repository_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"issues_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"merge_requests_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"forking_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"wiki_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"snippets_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"builds_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+			"pages_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"disabled", "private", "enabled", "public"}, false),
+			},
+			"operations_access_level": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateProjectAccessLevel,
+			},
+
+			// The merge request settings keep the ones of the project, or of
+			// GitLab on creation, unless set.
+			"merge_method": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"merge", "rebase_merge", "ff"}, false),
+			},
+			"only_allow_merge_if_pipeline_succeeds": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"only_allow_merge_if_all_discussions_are_resolved": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"remove_source_branch_after_merge": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"squash_option": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"never", "always", "default_on", "default_off"}, false),
+			},
+			"printing_merge_request_link_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"ci_config_path": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// The CI/CD settings, some of which control who may run pipelines
+			// and see their results, also keep their current value unless set.
+			"build_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntBetween(600, 2592000),
+			},
+			"build_git_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"fetch", "clone"}, false),
+			},
+			"auto_cancel_pending_pipelines": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"enabled", "disabled"}, false),
+			},
+			"build_coverage_regex": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"public_builds": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"shared_runners_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			// Whether Auto DevOps is enabled and how it deploys default to
+			// instance settings, which are kept unless set.
+			"auto_devops_enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"auto_devops_deploy_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"continuous", "manual", "timed_incremental"}, false),
+			},
+			"ci_default_git_depth": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntBetween(0, 1000),
+			},
+
+			// Attributes which only apply when the project is created, so
+			// changing them afterwards is ignored.
+			"import_url": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        true,
+				ConflictsWith:    []string{"forked_from_project", "template_name", "template_project_id"},
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"forked_from_project": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"template_name", "template_project_id"},
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"initialize_with_readme": {
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"template_name": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"template_project_id"},
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"use_custom_template": {
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"group_with_project_templates_id": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				DiffSuppressFunc: suppressAfterCreation,
+			},
+			"template_project_id": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				DiffSuppressFunc: suppressAfterCreation,
+			},
 
 			"ssh_url_to_repo": {
 				Type:     schema.TypeString,
@@ -89,39 +318,348 @@ func resourceGitlabProject() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"runners_token": {
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"avatar_url": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"path_with_namespace": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"namespace_full_path": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"archived": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"star_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"forks_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"repository_size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"lfs_objects_size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"job_artifacts_size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"import_status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"import_error": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"forked_from_project_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"archive_on_destroy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
 
-func resourceGitlabProjectSetToState(d *schema.ResourceData, project *gitlab.Project) {
+// resourceGitlabProjectFeatures maps the project attributes which are not
+// supported by every GitLab version to the feature they need.
+var resourceGitlabProjectFeatures = map[string]string{
+	"squash_option":               "squash_option",
+	"auto_devops_deploy_strategy": "auto_devops_deploy_strategy",
+	"ci_default_git_depth":        "ci_default_git_depth",
+	"repository_access_level":     "project_access_levels",
+	"issues_access_level":         "project_access_levels",
+	"merge_requests_access_level": "project_access_levels",
+	"forking_access_level":        "project_access_levels",
+	"wiki_access_level":           "project_access_levels",
+	"snippets_access_level":       "project_access_levels",
+	"builds_access_level":         "project_access_levels",
+	"pages_access_level":          "project_access_levels",
+	"operations_access_level":     "operations_access_level",
+
+	"use_custom_template":             "custom_project_templates",
+	"group_with_project_templates_id": "group_project_templates",
+}
+
+// suppressAfterCreation suppresses the diff of an attribute which only applies
+// when the project is created, once it exists: changing or removing it, for
+// instance to drop the credentials of import_url, must neither replace the
+// project, which would delete its repository, issues and merge requests, nor
+// pretend to change it.
+func suppressAfterCreation(k, old, new string, d *schema.ResourceData) bool {
+	return d.Id() != ""
+}
+
+// validateProjectAccessLevel validates the access level of a project feature
+// other than pages, which alone can be public.
+var validateProjectAccessLevel = validation.StringInSlice([]string{"disabled", "private", "enabled"}, false)
+
+// resourceGitlabProjectAvatarHash returns the SHA-256 hash of the avatar file
+// at path. A file which cannot be read is left to fail when uploaded.
+func resourceGitlabProjectAvatarHash(v interface{}) string {
+	path := v.(string)
+	if path == "" {
+		return ""
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return path
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// resourceGitlabProjectTopics returns the topics set on the project, which
+// GitLab calls tags before version 14.0.
+func resourceGitlabProjectTopics(d *schema.ResourceData) *[]string {
+	topics := []string{}
+	for _, topic := range d.Get("topics").(*schema.Set).List() {
+		topics = append(topics, topic.(string))
+	}
+	sort.Strings(topics)
+	return &topics
+}
+
+// resourceGitlabProjectBools lists the optional and computed booleans of a
+// project, which keep their current value unless set.
+var resourceGitlabProjectBools = []string{
+	"only_allow_merge_if_pipeline_succeeds",
+	"only_allow_merge_if_all_discussions_are_resolved",
+	"remove_source_branch_after_merge",
+	"printing_merge_request_link_enabled",
+	"public_builds",
+	"shared_runners_enabled",
+	"auto_devops_enabled",
+	"container_registry_enabled",
+	"lfs_enabled",
+	"request_access_enabled",
+}
+
+// resourceGitlabProjectSetBool sets the option matching attr, one of
+// resourceGitlabProjectBools, to value.
+func resourceGitlabProjectSetBool(options *projectOptions, attr string, value bool) {
+	switch attr {
+	case "only_allow_merge_if_pipeline_succeeds":
+		options.OnlyAllowMergeIfPipelineSucceeds = gitlab.Bool(value)
+	case "only_allow_merge_if_all_discussions_are_resolved":
+		options.OnlyAllowMergeIfAllDiscussionsAreResolved = gitlab.Bool(value)
+	case "remove_source_branch_after_merge":
+		options.RemoveSourceBranchAfterMerge = gitlab.Bool(value)
+	case "printing_merge_request_link_enabled":
+		options.PrintingMergeRequestLinkEnabled = gitlab.Bool(value)
+	case "public_builds":
+		options.PublicJobs = gitlab.Bool(value)
+	case "shared_runners_enabled":
+		options.SharedRunnersEnabled = gitlab.Bool(value)
+	case "auto_devops_enabled":
+		options.AutoDevopsEnabled = gitlab.Bool(value)
+	case "container_registry_enabled":
+		options.ContainerRegistryEnabled = gitlab.Bool(value)
+	case "lfs_enabled":
+		options.LFSEnabled = gitlab.Bool(value)
+	case "request_access_enabled":
+		options.RequestAccessEnabled = gitlab.Bool(value)
+	}
+}
+
+// resourceGitlabProjectAccessLevel returns the access level set in attr, or
+// nil when it is not set and GitLab keeps the current one.
+func resourceGitlabProjectAccessLevel(d *schema.ResourceData, attr string) *string {
+	if v, ok := d.GetOk(attr); ok {
+		return gitlab.String(v.(string))
+	}
+	return nil
+}
+
+// resourceGitlabProjectFeature returns what to send for a feature which has
+// both a boolean and an access level: the access level when it is set, the
+// boolean otherwise.
+func resourceGitlabProjectFeature(d *schema.ResourceData, feature string) (*bool, *string) {
+	if level := resourceGitlabProjectAccessLevel(d, feature+"_access_level"); level != nil {
+		return nil, level
+	}
+	return gitlab.Bool(d.Get(feature + "_enabled").(bool)), nil
+}
+
+func resourceGitlabProjectSetToState(d *schema.ResourceData, project *gitlabProject) {
 	d.SetId(fmt.Sprintf("%d", project.ID))
 	d.Set("name", project.Name)
 	d.Set("path", project.Path)
 	d.Set("description", project.Description)
 	d.Set("default_branch", project.DefaultBranch)
-	d.Set("issues_enabled", project.IssuesEnabled)
-	d.Set("merge_requests_enabled", project.MergeRequestsEnabled)
-	d.Set("wiki_enabled", project.WikiEnabled)
-	d.Set("snippets_enabled", project.SnippetsEnabled)
+
+	enabled := map[string]bool{
+		"issues":         project.IssuesEnabled,
+		"merge_requests": project.MergeRequestsEnabled,
+		"wiki":           project.WikiEnabled,
+		"snippets":       project.SnippetsEnabled,
+	}
+	for feature, v := range enabled {
+		if _, ok := d.GetOk(feature + "_access_level"); !ok {
+			d.Set(feature+"_enabled", v)
+		}
+	}
+
 	d.Set("visibility_level", string(project.Visibility))
+	d.Set("container_registry_enabled", project.ContainerRegistryEnabled)
+	d.Set("lfs_enabled", project.LFSEnabled)
+	d.Set("request_access_enabled", project.RequestAccessEnabled)
+
+	levels := map[string]string{
+		"repository_access_level":     project.RepositoryAccessLevel,
+		"issues_access_level":         project.IssuesAccessLevel,
+		"merge_requests_access_level": project.MergeRequestsAccessLevel,
+		"forking_access_level":        project.ForkingAccessLevel,
+		"wiki_access_level":           project.WikiAccessLevel,
+		"snippets_access_level":       project.SnippetsAccessLevel,
+		"builds_access_level":         project.BuildsAccessLevel,
+		"pages_access_level":          project.PagesAccessLevel,
+		"operations_access_level":     project.OperationsAccessLevel,
+	}
+	for attr, level := range levels {
+		if _, ok := d.GetOk(attr); ok {
+			d.Set(attr, level)
+		}
+	}
+
+	d.Set("merge_method", project.MergeMethod)
+	d.Set("only_allow_merge_if_pipeline_succeeds", project.OnlyAllowMergeIfPipelineSucceeds)
+	d.Set("only_allow_merge_if_all_discussions_are_resolved", project.OnlyAllowMergeIfAllDiscussionsAreResolved)
+	d.Set("remove_source_branch_after_merge", project.RemoveSourceBranchAfterMerge)
+	d.Set("squash_option", project.SquashOption)
+	d.Set("printing_merge_request_link_enabled", project.PrintingMergeRequestLinkEnabled)
+	d.Set("ci_config_path", project.CIConfigPath)
+	d.Set("build_timeout", project.BuildTimeout)
+	d.Set("build_git_strategy", project.BuildGitStrategy)
+	d.Set("auto_cancel_pending_pipelines", project.AutoCancelPendingPipelines)
+	d.Set("build_coverage_regex", project.BuildCoverageRegex)
+	d.Set("public_builds", project.PublicJobs)
+	d.Set("shared_runners_enabled", project.SharedRunnersEnabled)
+	d.Set("auto_devops_enabled", project.AutoDevopsEnabled)
+	d.Set("auto_devops_deploy_strategy", project.AutoDevopsDeployStrategy)
+	d.Set("ci_default_git_depth", project.CIDefaultGitDepth)
 	d.Set("namespace_id", project.Namespace.ID)
 
 	d.Set("ssh_url_to_repo", project.SSHURLToRepo)
 	d.Set("http_url_to_repo", project.HTTPURLToRepo)
 	d.Set("web_url", project.WebURL)
+	d.Set("runners_token", project.RunnersToken)
+	d.Set("avatar_url", project.AvatarURL)
+
+	// GitLab 14.0 renamed tags to topics, and still returns both.
+	if project.Topics != nil {
+		d.Set("topics", project.Topics)
+	} else {
+		d.Set("topics", project.TagList)
+	}
+
+	d.Set("path_with_namespace", project.PathWithNamespace)
+	if project.Namespace.FullPath != "" {
+		d.Set("namespace_full_path", project.Namespace.FullPath)
+	} else {
+		d.Set("namespace_full_path", strings.TrimSuffix(project.PathWithNamespace, "/"+project.Path))
+	}
+	if project.CreatedAt != nil {
+		d.Set("created_at", project.CreatedAt.Format(time.RFC3339))
+	}
+	d.Set("archived", project.Archived)
+	d.Set("star_count", project.StarCount)
+	d.Set("forks_count", project.ForksCount)
+
+	// Statistics are only given to project members with at least the
+	// reporter role.
+	if project.Statistics != nil {
+		d.Set("repository_size", int(project.Statistics.RepositorySize))
+		d.Set("lfs_objects_size", int(project.Statistics.LfsObjectsSize))
+		d.Set("job_artifacts_size", int(project.Statistics.JobArtifactsSize))
+	}
+
+	d.Set("import_status", project.ImportStatus)
+	d.Set("import_error", project.ImportError)
+	if project.ForkedFromProject != nil {
+		d.Set("forked_from_project_id", project.ForkedFromProject.ID)
+	} else {
+		d.Set("forked_from_project_id", 0)
+	}
 }
 
 func resourceGitlabProjectCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	options := &gitlab.CreateProjectOptions{
-		Name:                 gitlab.String(d.Get("name").(string)),
-		IssuesEnabled:        gitlab.Bool(d.Get("issues_enabled").(bool)),
-		MergeRequestsEnabled: gitlab.Bool(d.Get("merge_requests_enabled").(bool)),
-		WikiEnabled:          gitlab.Bool(d.Get("wiki_enabled").(bool)),
-		SnippetsEnabled:      gitlab.Bool(d.Get("snippets_enabled").(bool)),
-		Visibility:           stringToVisibilityLevel(d.Get("visibility_level").(string)),
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := checkAttributeFeatures(d, meta, resourceGitlabProjectFeatures); err != nil {
+		return err
 	}
 
+	// An empty project has no branch to be the default one, so fail before
+	// creating it rather than leave a tainted project behind.
+	_, withReadme := d.GetOk("initialize_with_readme")
+	_, imported := d.GetOk("import_url")
+	_, forked := d.GetOk("forked_from_project")
+	_, templated := d.GetOk("template_name")
+	_, fromProject := d.GetOk("template_project_id")
+	templated = templated || fromProject
+	if v, ok := d.GetOk("default_branch"); ok && !withReadme && !imported && !forked && !templated {
+		return fmt.Errorf("cannot make %q the default branch of project %q: the project is created empty. Push the branch before setting default_branch, or set initialize_with_readme", v, d.Get("name"))
+	}
+
+	// GitLab only looks up custom templates, of the instance or of a group,
+	// when use_custom_template is set.
+	if _, ok := d.GetOk("group_with_project_templates_id"); ok && !d.Get("use_custom_template").(bool) {
+		return fmt.Errorf("group_with_project_templates_id requires use_custom_template to be true")
+	}
+	if fromProject && !d.Get("use_custom_template").(bool) {
+		return fmt.Errorf("template_project_id requires use_custom_template to be true")
+	}
+
+	options := &projectOptions{
+		CreateProjectOptions: gitlab.CreateProjectOptions{
+			Name:       gitlab.String(d.Get("name").(string)),
+			Visibility: stringToVisibilityLevel(d.Get("visibility_level").(string)),
+		},
+		RepositoryAccessLevel: resourceGitlabProjectAccessLevel(d, "repository_access_level"),
+		ForkingAccessLevel:    resourceGitlabProjectAccessLevel(d, "forking_access_level"),
+		BuildsAccessLevel:     resourceGitlabProjectAccessLevel(d, "builds_access_level"),
+		PagesAccessLevel:      resourceGitlabProjectAccessLevel(d, "pages_access_level"),
+		OperationsAccessLevel: resourceGitlabProjectAccessLevel(d, "operations_access_level"),
+	}
+
+	options.IssuesEnabled, options.IssuesAccessLevel = resourceGitlabProjectFeature(d, "issues")
+	options.MergeRequestsEnabled, options.MergeRequestsAccessLevel = resourceGitlabProjectFeature(d, "merge_requests")
+	options.WikiEnabled, options.WikiAccessLevel = resourceGitlabProjectFeature(d, "wiki")
+	options.SnippetsEnabled, options.SnippetsAccessLevel = resourceGitlabProjectFeature(d, "snippets")
+
 	if v, ok := d.GetOk("path"); ok {
 		options.Path = gitlab.String(v.(string))
 	}
@@ -134,31 +672,247 @@ func resourceGitlabProjectCreate(d *schema.ResourceData, meta interface{}) error
 		options.Description = gitlab.String(v.(string))
 	}
 
-	log.Printf("[DEBUG] create gitlab project %q", options.Name)
+	if v, ok := d.GetOk("merge_method"); ok {
+		options.MergeMethod = gitlab.String(v.(string))
+	}
 
-	project, _, err := client.Projects.CreateProject(options)
-	if err != nil {
-		return err
+	if v, ok := d.GetOk("squash_option"); ok {
+		options.SquashOption = gitlab.String(v.(string))
+	}
+
+	for _, attr := range resourceGitlabProjectBools {
+		if d.Get(attr).(bool) {
+			resourceGitlabProjectSetBool(options, attr, true)
+		}
+	}
+
+	if v, ok := d.GetOk("ci_config_path"); ok {
+		options.CIConfigPath = gitlab.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("build_timeout"); ok {
+		options.BuildTimeout = gitlab.Int(v.(int))
+	}
+
+	if v, ok := d.GetOk("build_git_strategy"); ok {
+		options.BuildGitStrategy = gitlab.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("auto_cancel_pending_pipelines"); ok {
+		options.AutoCancelPendingPipelines = gitlab.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("build_coverage_regex"); ok {
+		options.BuildCoverageRegex = gitlab.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("auto_devops_deploy_strategy"); ok {
+		options.AutoDevopsDeployStrategy = gitlab.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("ci_default_git_depth"); ok {
+		options.CIDefaultGitDepth = gitlab.Int(v.(int))
+	}
+
+	if v, ok := d.GetOk("import_url"); ok {
+		options.ImportURL = gitlab.String(v.(string))
+	}
+
+	if _, ok := d.GetOk("topics"); ok {
+		options.TagList = resourceGitlabProjectTopics(d)
+		options.Topics = options.TagList
+	}
+
+	if v, ok := d.GetOk("template_name"); ok {
+		options.TemplateName = gitlab.String(v.(string))
+	}
+
+	if d.Get("use_custom_template").(bool) {
+		options.UseCustomTemplate = gitlab.Bool(true)
+	}
+
+	if v, ok := d.GetOk("group_with_project_templates_id"); ok {
+		options.GroupWithProjectTemplatesID = gitlab.Int(v.(int))
+	}
+
+	if v, ok := d.GetOk("template_project_id"); ok {
+		options.TemplateProjectID = gitlab.Int(v.(int))
+	}
+
+	if d.Get("initialize_with_readme").(bool) {
+		options.InitializeWithReadme = gitlab.Bool(true)
+
+		// Recent GitLab versions create the README on the default branch
+		// rather than on the instance default one.
+		if v, ok := d.GetOk("default_branch"); ok {
+			options.DefaultBranch = gitlab.String(v.(string))
+		}
+	}
+
+	source, fork := d.GetOk("forked_from_project")
+
+	var project *gitlab.Project
+	var err error
+	if fork {
+		forkOptions := &forkProjectOptions{Name: options.Name, Path: options.Path}
+		if options.NamespaceID != nil {
+			forkOptions.Namespace = *options.NamespaceID
+		}
+
+		log.Printf("[DEBUG] fork gitlab project %s as %q", source, *options.Name)
+
+		project, _, err = forkProject(client, source.(string), forkOptions, sudo...)
+		if err != nil {
+			return apiError(err, "forking project %s", source)
+		}
+	} else {
+		log.Printf("[DEBUG] create gitlab project %q", *options.Name)
+
+		project, _, err = createProject(client, options, sudo...)
+		if err != nil {
+			return apiError(err, "creating project %q", *options.Name)
+		}
 	}
 
 	d.SetId(fmt.Sprintf("%d", project.ID))
 
+	if options.ImportURL != nil || fork || templated {
+		if err := resourceGitlabProjectWaitForImport(d, meta); err != nil {
+			return err
+		}
+	}
+
+	// A fork starts out with the settings of the project it is forked from,
+	// so the configured ones are applied once it is ready.
+	edit := &projectOptions{}
+	if fork {
+		edit = options
+		edit.NamespaceID = nil
+	}
+
+	// Only the booleans set to true are given on creation, as GetOk does not
+	// tell the ones set to false from the ones left unset, which keep the
+	// defaults of GitLab. Until the project is read, its state only records
+	// the configured values, so the ones set to false are applied now.
+	disabled := false
+	state := d.State().Attributes
+	for _, attr := range resourceGitlabProjectBools {
+		if state[attr] == "false" {
+			resourceGitlabProjectSetBool(edit, attr, false)
+			disabled = true
+		}
+	}
+
+	if fork || disabled {
+		log.Printf("[DEBUG] update gitlab project %s after creating it", d.Id())
+
+		_, _, err := editProject(client, d.Id(), edit, sudo...)
+		if err != nil {
+			return apiError(err, "updating project %s", d.Id())
+		}
+	}
+
+	if v, ok := d.GetOk("avatar"); ok {
+		if err := resourceGitlabProjectUploadAvatar(d, meta, v.(string)); err != nil {
+			return err
+		}
+	}
+
+	// The repository is now populated, so the default branch can be checked
+	// and set.
+	if v, ok := d.GetOk("default_branch"); ok {
+		if err := resourceGitlabProjectCheckBranch(d, meta, v.(string)); err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] set the default branch of gitlab project %s to %q", d.Id(), v)
+
+		_, _, err := editProject(client, d.Id(), &projectOptions{CreateProjectOptions: gitlab.CreateProjectOptions{DefaultBranch: gitlab.String(v.(string))}}, sudo...)
+		if err != nil {
+			return apiError(err, "updating project %s", d.Id())
+		}
+	}
+
 	return resourceGitlabProjectRead(d, meta)
 }
 
+func resourceGitlabProjectUploadAvatar(d *schema.ResourceData, meta interface{}, path string) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	log.Printf("[DEBUG] upload %s as the avatar of gitlab project %s", path, d.Id())
+
+	_, _, err := uploadProjectAvatar(client, d.Id(), path, sudo...)
+	if err != nil {
+		return apiError(err, "uploading the avatar of project %s", d.Id())
+	}
+	return nil
+}
+
+// resourceGitlabProjectCheckBranch returns an error when branch does not
+// exist in the project. GitLab silently ignores a default branch which does
+// not exist, which would leave a diff behind after every apply.
+func resourceGitlabProjectCheckBranch(d *schema.ResourceData, meta interface{}, branch string) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	_, _, err := client.Branches.GetBranch(d.Id(), branch, sudo...)
+	if isNotFound(err) {
+		return fmt.Errorf("cannot make %q the default branch of project %s: the branch does not exist. Push it first, or create the project with initialize_with_readme", branch, d.Id())
+	}
+	if err != nil {
+		return apiError(err, "reading branch %q of project %s", branch, d.Id())
+	}
+	return nil
+}
+
+// resourceGitlabProjectWaitForImport waits until GitLab has filled the
+// repository of a project created from an import URL, as a fork or from a
+// template. A failed import is reported along with the error GitLab gives.
+func resourceGitlabProjectWaitForImport(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	stateConf := &resource.StateChangeConf{
+		// GitLab versions which do not report an import status have nothing
+		// to wait for.
+		Pending: []string{"scheduled", "started"},
+		Target:  []string{"finished", "none", ""},
+		Refresh: func() (interface{}, string, error) {
+			project, _, err := getProject(client, d.Id(), nil, sudo...)
+			if err != nil {
+				return nil, "Error", apiError(err, "reading project %s", d.Id())
+			}
+			if project.ImportStatus == "failed" {
+				return project, project.ImportStatus, fmt.Errorf("the repository of project %s could not be imported: %s", d.Id(), project.ImportError)
+			}
+			return project, project.ImportStatus, nil
+		},
+
+		Timeout:    d.Timeout(schema.TimeoutCreate),
+		MinTimeout: 3 * time.Second,
+	}
+
+	if _, err := stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("error waiting for the repository of project %s to be imported: %s", d.Id(), err)
+	}
+	return nil
+}
+
 func resourceGitlabProjectRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	log.Printf("[DEBUG] read gitlab project %s", d.Id())
 
-	project, response, err := client.Projects.GetProject(d.Id())
+	project, _, err := getProject(client, d.Id(), &getProjectOptions{Statistics: gitlab.Bool(true)}, sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing project %s from state because it no longer exists in gitlab", d.Id())
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading project %s", d.Id())
 	}
 
 	resourceGitlabProjectSetToState(d, project)
@@ -166,9 +920,26 @@ func resourceGitlabProjectRead(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabProjectUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 
-	options := &gitlab.EditProjectOptions{}
+	if err := checkAttributeFeatures(d, meta, resourceGitlabProjectFeatures); err != nil {
+		return err
+	}
+
+	if v := d.Get("default_branch").(string); d.HasChange("default_branch") && v != "" {
+		if err := resourceGitlabProjectCheckBranch(d, meta, v); err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("namespace_id") {
+		if err := resourceGitlabProjectTransfer(d, meta); err != nil {
+			return err
+		}
+	}
+
+	options := &projectOptions{}
 
 	if d.HasChange("name") {
 		options.Name = gitlab.String(d.Get("name").(string))
@@ -182,75 +953,253 @@ func resourceGitlabProjectUpdate(d *schema.ResourceData, meta interface{}) error
 		options.Description = gitlab.String(d.Get("description").(string))
 	}
 
-	if d.HasChange("default_branch") {
-		options.DefaultBranch = gitlab.String(d.Get("description").(string))
+	if v := d.Get("default_branch").(string); d.HasChange("default_branch") && v != "" {
+		options.DefaultBranch = gitlab.String(v)
+	}
+
+	if d.HasChange("topics") {
+		options.TagList = resourceGitlabProjectTopics(d)
+		options.Topics = options.TagList
+	}
+
+	avatar := d.Get("avatar").(string)
+	if d.HasChange("avatar") && avatar == "" {
+		options.Avatar = gitlab.String("")
 	}
 
 	if d.HasChange("visibility_level") {
 		options.Visibility = stringToVisibilityLevel(d.Get("visibility_level").(string))
 	}
 
-	if d.HasChange("issues_enabled") {
-		options.IssuesEnabled = gitlab.Bool(d.Get("issues_enabled").(bool))
+	if d.HasChange("issues_enabled") || d.HasChange("issues_access_level") {
+		options.IssuesEnabled, options.IssuesAccessLevel = resourceGitlabProjectFeature(d, "issues")
+	}
+
+	if d.HasChange("merge_requests_enabled") || d.HasChange("merge_requests_access_level") {
+		options.MergeRequestsEnabled, options.MergeRequestsAccessLevel = resourceGitlabProjectFeature(d, "merge_requests")
+	}
+
+	if d.HasChange("wiki_enabled") || d.HasChange("wiki_access_level") {
+		options.WikiEnabled, options.WikiAccessLevel = resourceGitlabProjectFeature(d, "wiki")
+	}
+
+	if d.HasChange("snippets_enabled") || d.HasChange("snippets_access_level") {
+		options.SnippetsEnabled, options.SnippetsAccessLevel = resourceGitlabProjectFeature(d, "snippets")
+	}
+
+	if d.HasChange("container_registry_enabled") {
+		options.ContainerRegistryEnabled = gitlab.Bool(d.Get("container_registry_enabled").(bool))
+	}
+
+	if d.HasChange("lfs_enabled") {
+		options.LFSEnabled = gitlab.Bool(d.Get("lfs_enabled").(bool))
+	}
+
+	if d.HasChange("request_access_enabled") {
+		options.RequestAccessEnabled = gitlab.Bool(d.Get("request_access_enabled").(bool))
+	}
+
+	if d.HasChange("repository_access_level") {
+		options.RepositoryAccessLevel = resourceGitlabProjectAccessLevel(d, "repository_access_level")
+	}
+
+	if d.HasChange("forking_access_level") {
+		options.ForkingAccessLevel = resourceGitlabProjectAccessLevel(d, "forking_access_level")
+	}
+
+	if d.HasChange("builds_access_level") {
+		options.BuildsAccessLevel = resourceGitlabProjectAccessLevel(d, "builds_access_level")
+	}
+
+	if d.HasChange("pages_access_level") {
+		options.PagesAccessLevel = resourceGitlabProjectAccessLevel(d, "pages_access_level")
+	}
+
+	if d.HasChange("operations_access_level") {
+		options.OperationsAccessLevel = resourceGitlabProjectAccessLevel(d, "operations_access_level")
+	}
+
+	if d.HasChange("merge_method") {
+		options.MergeMethod = gitlab.String(d.Get("merge_method").(string))
+	}
+
+	if d.HasChange("only_allow_merge_if_pipeline_succeeds") {
+		options.OnlyAllowMergeIfPipelineSucceeds = gitlab.Bool(d.Get("only_allow_merge_if_pipeline_succeeds").(bool))
+	}
+
+	if d.HasChange("only_allow_merge_if_all_discussions_are_resolved") {
+		options.OnlyAllowMergeIfAllDiscussionsAreResolved = gitlab.Bool(d.Get("only_allow_merge_if_all_discussions_are_resolved").(bool))
 	}
 
-	if d.HasChange("merge_requests_enabled") {
-		options.MergeRequestsEnabled = gitlab.Bool(d.Get("merge_requests_enabled").(bool))
+	if d.HasChange("remove_source_branch_after_merge") {
+		options.RemoveSourceBranchAfterMerge = gitlab.Bool(d.Get("remove_source_branch_after_merge").(bool))
 	}
 
-	if d.HasChange("wiki_enabled") {
-		options.WikiEnabled = gitlab.Bool(d.Get("wiki_enabled").(bool))
+	if d.HasChange("squash_option") {
+		options.SquashOption = gitlab.String(d.Get("squash_option").(string))
 	}
 
-	if d.HasChange("snippets_enabled") {
-		options.SnippetsEnabled = gitlab.Bool(d.Get("snippets_enabled").(bool))
+	if d.HasChange("printing_merge_request_link_enabled") {
+		options.PrintingMergeRequestLinkEnabled = gitlab.Bool(d.Get("printing_merge_request_link_enabled").(bool))
+	}
+
+	if d.HasChange("ci_config_path") {
+		options.CIConfigPath = gitlab.String(d.Get("ci_config_path").(string))
+	}
+
+	if d.HasChange("build_timeout") {
+		options.BuildTimeout = gitlab.Int(d.Get("build_timeout").(int))
+	}
+
+	if d.HasChange("build_git_strategy") {
+		options.BuildGitStrategy = gitlab.String(d.Get("build_git_strategy").(string))
+	}
+
+	if d.HasChange("auto_cancel_pending_pipelines") {
+		options.AutoCancelPendingPipelines = gitlab.String(d.Get("auto_cancel_pending_pipelines").(string))
+	}
+
+	if d.HasChange("build_coverage_regex") {
+		options.BuildCoverageRegex = gitlab.String(d.Get("build_coverage_regex").(string))
+	}
+
+	if d.HasChange("public_builds") {
+		options.PublicJobs = gitlab.Bool(d.Get("public_builds").(bool))
+	}
+
+	if d.HasChange("shared_runners_enabled") {
+		options.SharedRunnersEnabled = gitlab.Bool(d.Get("shared_runners_enabled").(bool))
+	}
+
+	if d.HasChange("auto_devops_enabled") {
+		options.AutoDevopsEnabled = gitlab.Bool(d.Get("auto_devops_enabled").(bool))
+	}
+
+	if d.HasChange("auto_devops_deploy_strategy") {
+		options.AutoDevopsDeployStrategy = gitlab.String(d.Get("auto_devops_deploy_strategy").(string))
+	}
+
+	if d.HasChange("ci_default_git_depth") {
+		options.CIDefaultGitDepth = gitlab.Int(d.Get("ci_default_git_depth").(int))
 	}
 
 	log.Printf("[DEBUG] update gitlab project %s", d.Id())
 
-	_, _, err := client.Projects.EditProject(d.Id(), options)
+	_, _, err := editProject(client, d.Id(), options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "updating project %s", d.Id())
+	}
+
+	if d.HasChange("avatar") && avatar != "" {
+		if err := resourceGitlabProjectUploadAvatar(d, meta, avatar); err != nil {
+			return err
+		}
 	}
 
 	return resourceGitlabProjectRead(d, meta)
 }
 
-func resourceGitlabProjectDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	log.Printf("[DEBUG] Delete gitlab project %s", d.Id())
+// resourceGitlabProjectTransfer moves the project to the namespace set in
+// namespace_id, keeping its repository, issues and merge requests, and waits
+// until GitLab reports it in its new namespace.
+func resourceGitlabProjectTransfer(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	namespace := d.Get("namespace_id").(int)
 
-	_, err := client.Projects.DeleteProject(d.Id())
-	if err != nil {
+	if err := meta.(*providerMeta).requireFeature("project_transfer", "Changing the namespace_id of a gitlab_project"); err != nil {
 		return err
 	}
 
-	// Wait for the project to be deleted.
-	// Deleting a project in gitlab is async.
+	log.Printf("[DEBUG] transfer gitlab project %s to namespace %d", d.Id(), namespace)
+
+	_, _, err := transferProject(client, d.Id(), &transferProjectOptions{Namespace: namespace}, sudo...)
+	if err != nil {
+		return apiError(err, "transferring project %s to namespace %d", d.Id(), namespace)
+	}
+
 	stateConf := &resource.StateChangeConf{
-		Pending: []string{"Deleting"},
-		Target:  []string{"Deleted"},
+		Pending: []string{"Transferring"},
+		Target:  []string{"Transferred"},
 		Refresh: func() (interface{}, string, error) {
-			out, response, err := client.Projects.GetProject(d.Id())
+			project, _, err := client.Projects.GetProject(d.Id(), sudo...)
 			if err != nil {
-				if response.StatusCode == 404 {
-					return out, "Deleted", nil
-				} else {
-					log.Printf("[ERROR] Received error: %#v", err)
-					return out, "Error", err
-				}
+				return nil, "Error", apiError(err, "reading project %s", d.Id())
 			}
-			return out, "Deleting", nil
+			if project.Namespace == nil || project.Namespace.ID != namespace {
+				return project, "Transferring", nil
+			}
+			return project, "Transferred", nil
 		},
 
-		Timeout:    10 * time.Minute,
+		Timeout:    d.Timeout(schema.TimeoutUpdate),
 		MinTimeout: 3 * time.Second,
-		Delay:      5 * time.Second,
 	}
 
-	_, err = stateConf.WaitForState()
-	if err != nil {
-		return fmt.Errorf("error waiting for project (%s) to become deleted: %s", d.Id(), err)
+	if _, err := stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("error waiting for project %s to be transferred to namespace %d: %s", d.Id(), namespace, err)
 	}
 	return nil
 }
+
+func resourceGitlabProjectImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	// Projects can be imported by full path as well as by ID, but the state
+	// always records the numeric ID, which does not change when the project
+	// is renamed or moved.
+	project, _, err := client.Projects.GetProject(d.Id(), sudo...)
+	if err != nil {
+		return nil, apiError(err, "importing project %s", d.Id())
+	}
+	d.SetId(fmt.Sprintf("%d", project.ID))
+
+	// Settings which only exist in Terraform are not read back from GitLab,
+	// so they start out with their defaults.
+	d.Set("deletion_protection", false)
+	d.Set("archive_on_destroy", false)
+	d.Set("initialize_with_readme", false)
+	d.Set("use_custom_template", false)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceGitlabProjectDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	if err := checkDeletionProtection(d, fmt.Sprintf("project %s", d.Id())); err != nil {
+		return err
+	}
+
+	if d.Get("archive_on_destroy").(bool) {
+		log.Printf("[DEBUG] archive gitlab project %s instead of deleting it", d.Id())
+
+		_, _, err := client.Projects.ArchiveProject(d.Id(), sudo...)
+		if err != nil && !isNotFound(err) {
+			return apiError(err, "archiving project %s", d.Id())
+		}
+		return nil
+	}
+
+	log.Printf("[DEBUG] Delete gitlab project %s", d.Id())
+
+	_, err := client.Projects.DeleteProject(d.Id(), sudo...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return apiError(err, "deleting project %s", d.Id())
+	}
+
+	// Wait for the project to be deleted.
+	// Deleting a project in gitlab is async.
+	return waitForDeletion(fmt.Sprintf("project %s", d.Id()), d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		project, _, err := getProject(client, d.Id(), nil, sudo...)
+		if err != nil {
+			return false, err
+		}
+		return project.MarkedForDeletionAt != "" || project.MarkedForDeletionOn != "", nil
+	})
+}
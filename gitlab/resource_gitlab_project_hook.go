@@ -15,11 +15,24 @@ func resourceGitlabProjectHook() *schema.Resource {
 		Read:   resourceGitlabProjectHookRead,
 		Update: resourceGitlabProjectHookUpdate,
 		Delete: resourceGitlabProjectHookDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceGitlabProjectHookImportState,
+		},
 
 		Schema: map[string]*schema.Schema{
-			"project": {
+			"project": projectSchema(),
+			"project_id": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"project_path": {
 				Type:     schema.TypeString,
-				Required: true,
+				Computed: true,
+			},
+			"project_paths": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 			"url": {
 				Type:     schema.TypeString,
@@ -75,13 +88,21 @@ func resourceGitlabProjectHook() *schema.Resource {
 				Optional: true,
 				Default:  true,
 			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
 
 func resourceGitlabProjectHookCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	options := &gitlab.AddProjectHookOptions{
 		URL:                   gitlab.String(d.Get("url").(string)),
 		PushEvents:            gitlab.Bool(d.Get("push_events").(bool)),
@@ -99,11 +120,11 @@ func resourceGitlabProjectHookCreate(d *schema.ResourceData, meta interface{}) e
 		options.Token = gitlab.String(v.(string))
 	}
 
-	log.Printf("[DEBUG] create gitlab project hook %q", options.URL)
+	log.Printf("[DEBUG] create gitlab project hook %q", *options.URL)
 
-	hook, _, err := client.Projects.AddProjectHook(project, options)
+	hook, _, err := client.Projects.AddProjectHook(project, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "creating hook %q in project %s", *options.URL, project)
 	}
 
 	d.SetId(fmt.Sprintf("%d", hook.ID))
@@ -112,23 +133,33 @@ func resourceGitlabProjectHookCreate(d *schema.ResourceData, meta interface{}) e
 }
 
 func resourceGitlabProjectHookRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	hookId, err := strconv.Atoi(d.Id())
 	if err != nil {
 		return err
 	}
+	if err := readProjectOf(d, client, sudo...); err != nil {
+		if isNotFound(err) {
+			log.Printf("[WARN] removing project hook %d from state because its project no longer exists in gitlab", hookId)
+			d.SetId("")
+			return nil
+		}
+
+		return apiError(err, "reading project %s", projectOf(d))
+	}
+	project := projectOf(d)
 	log.Printf("[DEBUG] read gitlab project hook %s/%d", project, hookId)
 
-	hook, response, err := client.Projects.GetProjectHook(project, hookId)
+	hook, _, err := client.Projects.GetProjectHook(project, hookId, sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing project hook %d from state because it no longer exists in gitlab", hookId)
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading hook %d of project %s", hookId, project)
 	}
 
 	d.Set("url", hook.URL)
@@ -145,8 +176,9 @@ func resourceGitlabProjectHookRead(d *schema.ResourceData, meta interface{}) err
 }
 
 func resourceGitlabProjectHookUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	project := projectOf(d)
 	hookId, err := strconv.Atoi(d.Id())
 	if err != nil {
 		return err
@@ -170,23 +202,44 @@ func resourceGitlabProjectHookUpdate(d *schema.ResourceData, meta interface{}) e
 
 	log.Printf("[DEBUG] update gitlab project hook %s", d.Id())
 
-	_, _, err = client.Projects.EditProjectHook(project, hookId, options)
+	_, _, err = client.Projects.EditProjectHook(project, hookId, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "updating hook %d of project %s", hookId, project)
 	}
 
 	return resourceGitlabProjectHookRead(d, meta)
 }
 
+func resourceGitlabProjectHookImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	project, id, err := parseProjectImportID(d.Id(), "hook_id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("unexpected format of ID (%s), hook_id must be a number", d.Id())
+	}
+
+	// GitLab never returns the token of a hook, so it stays empty until it
+	// is set in the configuration and applied.
+	d.Set("project", project)
+	d.SetId(id)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGitlabProjectHookDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
-	project := d.Get("project").(string)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	project := projectOf(d)
 	hookId, err := strconv.Atoi(d.Id())
 	if err != nil {
 		return err
 	}
 	log.Printf("[DEBUG] Delete gitlab project hook %s", d.Id())
 
-	_, err = client.Projects.DeleteProjectHook(project, hookId)
-	return err
+	_, err = client.Projects.DeleteProjectHook(project, hookId, sudo...)
+	if err != nil && !isNotFound(err) {
+		return apiError(err, "deleting hook %d of project %s", hookId, project)
+	}
+	return nil
 }
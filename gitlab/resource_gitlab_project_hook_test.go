@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"testing"
 
@@ -67,6 +68,154 @@ func TestAccGitlabProjectHook_basic(t *testing.T) {
 	})
 }
 
+func TestAccGitlabProjectHook_import(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectHookDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectHookImportConfig(rInt),
+			},
+			{
+				ResourceName:            "gitlab_project_hook.foo",
+				ImportState:             true,
+				ImportStateIdPrefix:     fmt.Sprintf("foo-group-%d/foo-%d:", rInt, rInt),
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"token"},
+			},
+			{
+				ResourceName:  "gitlab_project_hook.foo",
+				ImportState:   true,
+				ImportStateId: fmt.Sprintf("foo-group-%d/foo-%d:hook", rInt, rInt),
+				ExpectError:   regexp.MustCompile("hook_id must be a number"),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProjectHook_projectPathOrID(t *testing.T) {
+	var project gitlab.Project
+	var hook gitlab.ProjectHook
+	var hookID int
+	rInt := acctest.RandInt()
+	path := fmt.Sprintf("foo-group-%d/foo-%d", rInt, rInt)
+	renamed := fmt.Sprintf("foo-group-%d/foo-renamed-%d", rInt, rInt)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectHookDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectHookProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+					resource.TestCheckResourceAttrPair("gitlab_project_hook.foo", "project_id", "gitlab_project.foo", "id"),
+					resource.TestCheckResourceAttr("gitlab_project_hook.foo", "project_path", path),
+				),
+			},
+			// Rename the project outside of Terraform, keeping the old path in
+			// the configuration
+			{
+				PreConfig: testAccRenameGitlabProject(t, &project, fmt.Sprintf("foo-renamed-%d", rInt)),
+				Config:    testAccGitlabProjectHookProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+					resource.TestCheckResourceAttr("gitlab_project_hook.foo", "project", path),
+					resource.TestCheckResourceAttr("gitlab_project_hook.foo", "project_path", renamed),
+				),
+			},
+			{
+				Config:   testAccGitlabProjectHookProjectConfig(rInt, path),
+				PlanOnly: true,
+			},
+			// Refer to the project by its new path
+			{
+				Config: testAccGitlabProjectHookProjectConfig(rInt, renamed),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+				),
+			},
+			// Refer to the same project by ID
+			{
+				Config: testAccGitlabProjectHookProjectConfig(rInt, "${gitlab_project.foo.id}"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProjectHook_projectRenamedAfterSwitch(t *testing.T) {
+	var project gitlab.Project
+	var hook gitlab.ProjectHook
+	var hookID int
+	rInt := acctest.RandInt()
+	path := fmt.Sprintf("foo-group-%d/foo-%d", rInt, rInt)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectHookDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectHookProjectConfig(rInt, "${gitlab_project.foo.id}"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+				),
+			},
+			// Switching to the path keeps the ID in the state
+			{
+				Config: testAccGitlabProjectHookProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+					resource.TestCheckResourceAttrPair("gitlab_project_hook.foo", "project", "gitlab_project.foo", "id"),
+				),
+			},
+			// Rename the project outside of Terraform, keeping the old path in
+			// the configuration
+			{
+				PreConfig: testAccRenameGitlabProject(t, &project, fmt.Sprintf("foo-renamed-%d", rInt)),
+				Config:    testAccGitlabProjectHookProjectConfig(rInt, path),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectHookExists("gitlab_project_hook.foo", &hook),
+					testAccCheckGitlabProjectHookNotRecreated(&hook, &hookID),
+					resource.TestCheckResourceAttr("gitlab_project_hook.foo", "project_paths.#", "2"),
+					resource.TestCheckResourceAttr("gitlab_project_hook.foo", "project_paths.0", path),
+				),
+			},
+			{
+				Config:   testAccGitlabProjectHookProjectConfig(rInt, path),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func testAccCheckGitlabProjectHookNotRecreated(hook *gitlab.ProjectHook, id *int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if *id == 0 {
+			*id = hook.ID
+		}
+		if hook.ID != *id {
+			return fmt.Errorf("got hook %d; want hook %d to be kept", hook.ID, *id)
+		}
+		return nil
+	}
+}
+
 func testAccCheckGitlabProjectHookExists(n string, hook *gitlab.ProjectHook) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -78,11 +227,11 @@ func testAccCheckGitlabProjectHookExists(n string, hook *gitlab.ProjectHook) res
 		if err != nil {
 			return err
 		}
-		repoName := rs.Primary.Attributes["project"]
+		repoName := rs.Primary.Attributes["project_id"]
 		if repoName == "" {
 			return fmt.Errorf("No project ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
 		gotHook, _, err := conn.Projects.GetProjectHook(repoName, hookID)
 		if err != nil {
@@ -153,20 +302,20 @@ func testAccCheckGitlabProjectHookAttributes(hook *gitlab.ProjectHook, want *tes
 }
 
 func testAccCheckGitlabProjectHookDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_project" {
 			continue
 		}
 
-		gotRepo, resp, err := conn.Projects.GetProject(rs.Primary.ID)
+		gotRepo, _, err := conn.Projects.GetProject(rs.Primary.ID)
 		if err == nil {
 			if gotRepo != nil && fmt.Sprintf("%d", gotRepo.ID) == rs.Primary.ID {
 				return fmt.Errorf("Repository still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -192,6 +341,61 @@ resource "gitlab_project_hook" "foo" {
 	`, rInt, rInt)
 }
 
+func testAccGitlabProjectHookImportConfig(rInt int) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-group-%d"
+  path = "foo-group-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  namespace_id = "${gitlab_group.foo.id}"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_project_hook" "foo" {
+  project = "${gitlab_project.foo.path_with_namespace}"
+  url = "https://example.com/hook-%d"
+  token = "secret-%d"
+  merge_requests_events = true
+}
+	`, rInt, rInt, rInt, rInt, rInt)
+}
+
+func testAccGitlabProjectHookProjectConfig(rInt int, project string) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foo-group-%d"
+  path = "foo-group-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  namespace_id = "${gitlab_group.foo.id}"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_project_hook" "foo" {
+  project = "%s"
+  url = "https://example.com/hook-%d"
+
+  # The project may be given by a literal path
+  depends_on = ["gitlab_project.foo"]
+}
+	`, rInt, rInt, rInt, project, rInt)
+}
+
 func testAccGitlabProjectHookUpdateConfig(rInt int) string {
 	return fmt.Sprintf(`
 resource "gitlab_project" "foo" {
@@ -2,6 +2,13 @@ package gitlab
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
@@ -106,10 +113,710 @@ func TestAccGitlabProject_nestedImport(t *testing.T) {
 				ImportState:       true,
 				ImportStateVerify: true,
 			},
+			{
+				ResourceName:      "gitlab_project.foo",
+				ImportState:       true,
+				ImportStateId:     fmt.Sprintf("foogroup-%d/foo-%d", rInt, rInt),
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_transfer(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectTransferConfig(rInt, "foo"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttrPair("gitlab_project.foo", "namespace_id", "gitlab_group.foo", "id"),
+				),
+			},
+			// Move the project to another group
+			{
+				Config: testAccGitlabProjectTransferConfig(rInt, "bar"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					func(s *terraform.State) error {
+						if want := fmt.Sprintf("bargroup-%d/foo-%d", rInt, rInt); project.PathWithNamespace != want {
+							return fmt.Errorf("got path_with_namespace %q; want %q", project.PathWithNamespace, want)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttrPair("gitlab_project.foo", "namespace_id", "gitlab_group.bar", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_deletionProtection(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, "deletion_protection = true"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+				),
+			},
+			// Deleting the project fails while it is protected
+			{
+				Config:      testAccGitlabProjectSettingsConfig(rInt, "deletion_protection = true"),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("is protected against deletion"),
+			},
+			// Lift the protection so that the project can be destroyed
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, "deletion_protection = false"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_archiveOnDestroy(t *testing.T) {
+	var project gitlab.Project
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectArchived(&project),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, "archive_on_destroy = true"),
+				Check:  testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_mergeRequestSettings(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  merge_method = "ff"
+  only_allow_merge_if_pipeline_succeeds = true
+  only_allow_merge_if_all_discussions_are_resolved = true
+  remove_source_branch_after_merge = false
+  squash_option = "always"
+  printing_merge_request_link_enabled = false`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "merge_method", "ff"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_pipeline_succeeds", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_all_discussions_are_resolved", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "remove_source_branch_after_merge", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "squash_option", "always"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "printing_merge_request_link_enabled", "false"),
+				),
+			},
+			// Settings left unset keep their value
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "merge_method", "ff"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_pipeline_succeeds", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_all_discussions_are_resolved", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "remove_source_branch_after_merge", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "squash_option", "always"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "printing_merge_request_link_enabled", "false"),
+				),
+			},
+			// Back to the defaults, in place
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  merge_method = "merge"
+  only_allow_merge_if_pipeline_succeeds = false
+  only_allow_merge_if_all_discussions_are_resolved = false
+  remove_source_branch_after_merge = true
+  squash_option = "default_off"
+  printing_merge_request_link_enabled = true`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "merge_method", "merge"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_pipeline_succeeds", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "only_allow_merge_if_all_discussions_are_resolved", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "remove_source_branch_after_merge", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "squash_option", "default_off"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "printing_merge_request_link_enabled", "true"),
+				),
+			},
 		},
 	})
 }
 
+func TestAccGitlabProject_pipelineSettings(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			// The Auto DevOps settings default to the ones of the instance
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_timeout", "3600"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_git_strategy", "fetch"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "shared_runners_enabled", "true"),
+					resource.TestCheckResourceAttrSet("gitlab_project.foo", "auto_devops_deploy_strategy"),
+					resource.TestCheckResourceAttrSet("gitlab_project.foo", "runners_token"),
+				),
+			},
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  ci_config_path = "ci/pipeline.yml"
+  build_timeout = 7200
+  build_git_strategy = "clone"
+  auto_cancel_pending_pipelines = "disabled"
+  build_coverage_regex = "Total: (\\d+\\.\\d+)%"
+  public_builds = false
+  shared_runners_enabled = false
+  auto_devops_enabled = false
+  auto_devops_deploy_strategy = "manual"
+  ci_default_git_depth = 0`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "ci_config_path", "ci/pipeline.yml"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_timeout", "7200"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_git_strategy", "clone"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_cancel_pending_pipelines", "disabled"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_coverage_regex", `Total: (\d+\.\d+)%`),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "public_builds", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "shared_runners_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_devops_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_devops_deploy_strategy", "manual"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "ci_default_git_depth", "0"),
+				),
+			},
+			// Settings left unset keep their value
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_timeout", "7200"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "build_git_strategy", "clone"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_cancel_pending_pipelines", "disabled"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "public_builds", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "shared_runners_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_devops_enabled", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_pipelineSettingsOnCreation(t *testing.T) {
+	var project gitlab.Project
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			// Booleans set to false are applied even though GitLab defaults
+			// them to true
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  public_builds = false
+  shared_runners_enabled = false
+  auto_devops_enabled = false`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "public_builds", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "shared_runners_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "auto_devops_enabled", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_accessLevels(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			// The access levels supersede the booleans, which are ignored
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  issues_access_level = "private"
+  wiki_access_level = "disabled"
+  builds_access_level = "private"
+  pages_access_level = "public"
+  container_registry_enabled = false
+  lfs_enabled = false
+  request_access_enabled = false`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "issues_access_level", "private"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "wiki_access_level", "disabled"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "builds_access_level", "private"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "pages_access_level", "public"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "container_registry_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "lfs_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "request_access_enabled", "false"),
+				),
+			},
+			// Back to the booleans, which turn the wiki on again
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					testAccCheckGitlabProjectAttributes(&project, &testAccGitlabProjectExpectedAttributes{
+						Name:                 fmt.Sprintf("foo-%d", rInt),
+						Path:                 fmt.Sprintf("foo.%d", rInt),
+						Description:          "Terraform acceptance tests",
+						IssuesEnabled:        true,
+						MergeRequestsEnabled: true,
+						WikiEnabled:          true,
+						SnippetsEnabled:      true,
+						Visibility:           gitlab.PublicVisibility,
+					}),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "wiki_access_level", ""),
+					// Booleans left unset keep their value
+					resource.TestCheckResourceAttr("gitlab_project.foo", "container_registry_enabled", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "lfs_enabled", "false"),
+				),
+			},
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  container_registry_enabled = true
+  lfs_enabled = true`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "container_registry_enabled", "true"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "lfs_enabled", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_defaultBranch(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  initialize_with_readme = true
+  default_branch = "main"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "main"),
+				),
+			},
+			{
+				PreConfig: func() {
+					conn := testAccProvider.Meta().(*providerMeta).client
+					_, _, err := conn.Branches.CreateBranch(projectID, &gitlab.CreateBranchOptions{
+						Branch: gitlab.String("develop"),
+						Ref:    gitlab.String("main"),
+					})
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  initialize_with_readme = true
+  default_branch = "develop"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "develop"),
+				),
+			},
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `
+  initialize_with_readme = true
+  default_branch = "missing"`),
+				ExpectError: regexp.MustCompile(`cannot make "missing" the default branch of project \d+: the branch does not exist`),
+			},
+			// Without a default branch, the one of the project is kept
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "develop"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_defaultBranchOfEmptyProject(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccGitlabProjectSettingsConfig(rInt, `default_branch = "main"`),
+				ExpectError: regexp.MustCompile("the project is created empty"),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_createOnlyIgnored(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `import_url = "https://gitlab.com/gitlab-org/gitlab-test.git"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+				),
+			},
+			// Removing a creation-only argument keeps the project
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+				),
+			},
+			// So does setting one
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `initialize_with_readme = true`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_topicsAndAvatar(t *testing.T) {
+	var project gitlab.Project
+	var projectID int
+	var avatarURL string
+	rInt := acctest.RandInt()
+
+	dir, err := ioutil.TempDir("", "gitlab-avatar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	avatar := filepath.Join(dir, "avatar.png")
+	testAccWriteGitlabProjectAvatar(t, avatar, color.RGBA{R: 255, A: 255})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, fmt.Sprintf(`
+  topics = ["terraform", "acceptance"]
+  avatar = %q`, avatar)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "topics.#", "2"),
+					testAccCheckGitlabProjectAvatarURL("gitlab_project.foo", &avatarURL, true),
+					resource.TestCheckResourceAttrSet("gitlab_project.foo", "path_with_namespace"),
+					resource.TestCheckResourceAttrSet("gitlab_project.foo", "namespace_full_path"),
+					resource.TestCheckResourceAttrSet("gitlab_project.foo", "created_at"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "archived", "false"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "star_count", "0"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "forks_count", "0"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "repository_size", "0"),
+				),
+			},
+			// A new image at the same path is uploaded again
+			{
+				PreConfig: func() {
+					testAccWriteGitlabProjectAvatar(t, avatar, color.RGBA{B: 255, A: 255})
+				},
+				Config: testAccGitlabProjectSettingsConfig(rInt, fmt.Sprintf(`
+  topics = ["terraform"]
+  avatar = %q`, avatar)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "topics.#", "1"),
+					testAccCheckGitlabProjectAvatarURL("gitlab_project.foo", &avatarURL, true),
+				),
+			},
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, ""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					// Topics left unset are kept
+					resource.TestCheckResourceAttr("gitlab_project.foo", "topics.#", "1"),
+					testAccCheckGitlabProjectAvatarURL("gitlab_project.foo", &avatarURL, false),
+				),
+			},
+			// An empty set removes them
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, "topics = []"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectNotRecreated(&project, &projectID),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "topics.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_template(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `template_name = "plainhtml"`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("gitlab_project.foo", "import_status", "finished"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "master"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_customTemplate(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheckPremium(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccGitlabProjectCustomTemplateConfig(rInt, false),
+				ExpectError: regexp.MustCompile("requires use_custom_template to be true"),
+			},
+			{
+				Config: testAccGitlabProjectCustomTemplateConfig(rInt, true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("gitlab_project.foo", "import_status", "finished"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "master"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_importURL(t *testing.T) {
+	var project gitlab.Project
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectSettingsConfig(rInt, `import_url = "https://gitlab.com/gitlab-org/gitlab-test.git"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "import_status", "finished"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "import_error", ""),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "default_branch", "master"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_importURLFailure(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccGitlabProjectSettingsConfig(rInt, `import_url = "https://unreachable.example.com/repo.git"`),
+				ExpectError: regexp.MustCompile("could not be imported"),
+			},
+		},
+	})
+}
+
+func TestAccGitlabProject_fork(t *testing.T) {
+	var project, fork gitlab.Project
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabProjectDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabProjectForkConfig(rInt),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckGitlabProjectExists("gitlab_project.foo", &project),
+					testAccCheckGitlabProjectExists("gitlab_project.fork", &fork),
+					resource.TestCheckResourceAttrPair("gitlab_project.fork", "forked_from_project_id", "gitlab_project.foo", "id"),
+					resource.TestCheckResourceAttr("gitlab_project.fork", "description", "Forked by Terraform acceptance tests"),
+					resource.TestCheckResourceAttr("gitlab_project.fork", "import_status", "finished"),
+					resource.TestCheckResourceAttr("gitlab_project.foo", "forked_from_project_id", "0"),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckGitlabProjectNotRecreated checks project keeps the ID it had
+// when first checked, i.e. that it was updated in place.
+func testAccCheckGitlabProjectNotRecreated(project *gitlab.Project, id *int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if *id == 0 {
+			*id = project.ID
+		}
+		if project.ID != *id {
+			return fmt.Errorf("got project %d; want project %d to be updated in place", project.ID, *id)
+		}
+		return nil
+	}
+}
+
+// testAccCheckGitlabProjectAvatarURL checks the project has an avatar, which
+// differs from the one it had when last checked, or no avatar at all.
+func testAccCheckGitlabProjectAvatarURL(n string, last *string, want bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not Found: %s", n)
+		}
+
+		url := rs.Primary.Attributes["avatar_url"]
+		switch {
+		case !want && url != "":
+			return fmt.Errorf("got avatar %q; want no avatar", url)
+		case want && url == "":
+			return fmt.Errorf("got no avatar; want one")
+		case want && url == *last:
+			return fmt.Errorf("got avatar %q again; want a new one", url)
+		}
+		*last = url
+		return nil
+	}
+}
+
+func testAccWriteGitlabProjectAvatar(t *testing.T, path string, c color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, c)
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testAccCheckGitlabProjectArchived checks project was archived rather than
+// deleted, then deletes it.
+func testAccCheckGitlabProjectArchived(project *gitlab.Project) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		conn := testAccProvider.Meta().(*providerMeta).client
+
+		gotProject, _, err := conn.Projects.GetProject(project.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Projects.DeleteProject(project.ID); err != nil {
+			return err
+		}
+
+		if !gotProject.Archived {
+			return fmt.Errorf("project %d was not archived", project.ID)
+		}
+		return nil
+	}
+}
+
+// testAccRenameGitlabProject changes the path of project behind Terraform's
+// back, as a rename in the GitLab UI would.
+func testAccRenameGitlabProject(t *testing.T, project *gitlab.Project, path string) func() {
+	return func() {
+		conn := testAccProvider.Meta().(*providerMeta).client
+		if _, _, err := conn.Projects.EditProject(project.ID, &gitlab.EditProjectOptions{Path: gitlab.String(path)}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+}
+
 func testAccCheckGitlabProjectExists(n string, project *gitlab.Project) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -121,7 +828,7 @@ func testAccCheckGitlabProjectExists(n string, project *gitlab.Project) resource
 		if repoName == "" {
 			return fmt.Errorf("No project ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
 		gotProject, _, err := conn.Projects.GetProject(repoName)
 		if err != nil {
@@ -185,20 +892,20 @@ func testAccCheckGitlabProjectAttributes(project *gitlab.Project, want *testAccG
 }
 
 func testAccCheckGitlabProjectDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_project" {
 			continue
 		}
 
-		gotRepo, resp, err := conn.Projects.GetProject(rs.Primary.ID)
+		gotRepo, _, err := conn.Projects.GetProject(rs.Primary.ID)
 		if err == nil {
 			if gotRepo != nil && fmt.Sprintf("%d", gotRepo.ID) == rs.Primary.ID {
 				return fmt.Errorf("Repository still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -226,6 +933,101 @@ resource "gitlab_project" "foo" {
 	`, rInt, rInt, rInt)
 }
 
+func testAccGitlabProjectTransferConfig(rInt int, group string) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foogroup-%d"
+  path = "foogroup-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_group" "bar" {
+  name = "bargroup-%d"
+  path = "bargroup-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  description = "Terraform acceptance tests"
+  namespace_id = "${gitlab_group.%s.id}"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+	`, rInt, rInt, rInt, rInt, rInt, group)
+}
+
+func testAccGitlabProjectForkConfig(rInt int) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "foo" {
+  name = "foogroup-%d"
+  path = "foogroup-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "fork" {
+  name = "foo-%d"
+  description = "Forked by Terraform acceptance tests"
+  namespace_id = "${gitlab_group.foo.id}"
+  forked_from_project = "${gitlab_project.foo.id}"
+  visibility_level = "public"
+}
+	`, rInt, rInt, rInt, rInt)
+}
+
+func testAccGitlabProjectCustomTemplateConfig(rInt int, useCustomTemplate bool) string {
+	return fmt.Sprintf(`
+resource "gitlab_group" "templates" {
+  name = "templates-%d"
+  path = "templates-%d"
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "template" {
+  name = "template-%d"
+  namespace_id = "${gitlab_group.templates.id}"
+  initialize_with_readme = true
+  visibility_level = "public"
+}
+
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  description = "Terraform acceptance tests"
+  use_custom_template = %t
+  group_with_project_templates_id = "${gitlab_group.templates.id}"
+  template_project_id = "${gitlab_project.template.id}"
+  visibility_level = "public"
+}
+	`, rInt, rInt, rInt, rInt, useCustomTemplate)
+}
+
+func testAccGitlabProjectSettingsConfig(rInt int, setting string) string {
+	return fmt.Sprintf(`
+resource "gitlab_project" "foo" {
+  name = "foo-%d"
+  path = "foo.%d"
+  description = "Terraform acceptance tests"
+
+  # So that acceptance tests can be run in a gitlab organization
+  # with no billing
+  visibility_level = "public"
+
+  %s
+}
+	`, rInt, rInt, setting)
+}
+
 func testAccGitlabProjectConfig(rInt int) string {
 	return fmt.Sprintf(`
 resource "gitlab_project" "foo" {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	gitlab "github.com/xanzy/go-gitlab"
@@ -15,6 +16,13 @@ func resourceGitlabUser() *schema.Resource {
 		Read:   resourceGitlabUserRead,
 		Update: resourceGitlabUserUpdate,
 		Delete: resourceGitlabUserDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceGitlabUserImportState,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"username": {
@@ -55,6 +63,10 @@ func resourceGitlabUser() *schema.Resource {
 				Optional: true,
 				Default:  0,
 			},
+			"sudo": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
 		},
 	}
 }
@@ -67,7 +79,11 @@ func resourceGitlabUserSetToState(d *schema.ResourceData, user *gitlab.User) {
 }
 
 func resourceGitlabUserCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := meta.(*providerMeta).requireAdmin("Creating a gitlab_user"); err != nil {
+		return err
+	}
 	options := &gitlab.CreateUserOptions{
 		Email:            gitlab.String(d.Get("email").(string)),
 		Password:         gitlab.String(d.Get("password").(string)),
@@ -79,11 +95,11 @@ func resourceGitlabUserCreate(d *schema.ResourceData, meta interface{}) error {
 		SkipConfirmation: gitlab.Bool(d.Get("skip_confirmation").(bool)),
 	}
 
-	log.Printf("[DEBUG] create gitlab user %q", options.Username)
+	log.Printf("[DEBUG] create gitlab user %q", *options.Username)
 
-	user, _, err := client.Users.CreateUser(options)
+	user, _, err := client.Users.CreateUser(options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "creating user %q", *options.Username)
 	}
 
 	d.SetId(fmt.Sprintf("%d", user.ID))
@@ -93,20 +109,21 @@ func resourceGitlabUserCreate(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabUserRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
 	log.Printf("[DEBUG] read gitlab user %s", d.Id())
 
 	id, _ := strconv.Atoi(d.Id())
 
-	user, response, err := client.Users.GetUser(id)
+	user, _, err := client.Users.GetUser(id, sudo...)
 	if err != nil {
-		if response.StatusCode == 404 {
+		if isNotFound(err) {
 			log.Printf("[WARN] removing user %s from state because it no longer exists in gitlab", d.Id())
 			d.SetId("")
 			return nil
 		}
 
-		return err
+		return apiError(err, "reading user %s", d.Id())
 	}
 
 	resourceGitlabUserSetToState(d, user)
@@ -114,7 +131,11 @@ func resourceGitlabUserRead(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceGitlabUserUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := meta.(*providerMeta).requireAdmin("Updating a gitlab_user"); err != nil {
+		return err
+	}
 
 	options := &gitlab.ModifyUserOptions{}
 
@@ -142,22 +163,82 @@ func resourceGitlabUserUpdate(d *schema.ResourceData, meta interface{}) error {
 
 	id, _ := strconv.Atoi(d.Id())
 
-	_, _, err := client.Users.ModifyUser(id, options)
+	_, _, err := client.Users.ModifyUser(id, options, sudo...)
 	if err != nil {
-		return err
+		return apiError(err, "updating user %s", d.Id())
 	}
 
 	return resourceGitlabUserRead(d, meta)
 }
 
+func resourceGitlabUserImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+
+	// Users can be imported by username as well as by ID.
+	var user *gitlab.User
+	if id, err := strconv.Atoi(d.Id()); err == nil {
+		user, _, err = client.Users.GetUser(id, sudo...)
+		if err != nil {
+			return nil, apiError(err, "importing user %s", d.Id())
+		}
+	} else {
+		users, _, err := client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String(d.Id())}, sudo...)
+		if err != nil {
+			return nil, apiError(err, "importing user %s", d.Id())
+		}
+		// GitLab matches usernames regardless of case, so prefer the user
+		// whose username is exactly the one given.
+		for _, u := range users {
+			if u.Username == d.Id() {
+				user = u
+				break
+			}
+		}
+		if user == nil {
+			switch len(users) {
+			case 0:
+				return nil, fmt.Errorf("Error importing user %s: no user with this username", d.Id())
+			case 1:
+				user = users[0]
+			default:
+				return nil, fmt.Errorf("Error importing user %s: %d users match this username, import it by ID instead", d.Id(), len(users))
+			}
+		}
+	}
+	d.SetId(fmt.Sprintf("%d", user.ID))
+
+	// Read does not refresh these, and the password is never returned, so
+	// the next apply stores the one from the configuration without changing
+	// it in GitLab. skip_confirmation only matters when the user is created.
+	d.Set("email", user.Email)
+	d.Set("is_admin", user.IsAdmin)
+	d.Set("skip_confirmation", true)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGitlabUserDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*gitlab.Client)
+	client := meta.(*providerMeta).client
+	sudo := sudoOptions(d, meta)
+	if err := meta.(*providerMeta).requireAdmin("Deleting a gitlab_user"); err != nil {
+		return err
+	}
 	log.Printf("[DEBUG] Delete gitlab user %s", d.Id())
 
 	id, _ := strconv.Atoi(d.Id())
 
-	_, err := client.Users.DeleteUser(id)
-	// Ignoring error due to some bug in library
-	log.Printf("[DEBUG] Delete gitlab user %s", err)
-	return nil
+	_, err := client.Users.DeleteUser(id, sudo...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return apiError(err, "deleting user %s", d.Id())
+	}
+
+	// Users are deleted in the background, along with their projects.
+	return waitForDeletion(fmt.Sprintf("user %s", d.Id()), d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		_, _, err := client.Users.GetUser(id, sudo...)
+		return false, err
+	})
 }
@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"testing"
 
@@ -75,6 +76,48 @@ func TestAccGitlabUser_basic(t *testing.T) {
 	})
 }
 
+func TestAccGitlabUser_import(t *testing.T) {
+	rInt := acctest.RandInt()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckGitlabUserDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccGitlabUserConfig(rInt),
+			},
+			{
+				ResourceName:            "gitlab_user.foo",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+			{
+				ResourceName:            "gitlab_user.foo",
+				ImportState:             true,
+				ImportStateId:           fmt.Sprintf("listest%d", rInt),
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+			// GitLab matches usernames regardless of case
+			{
+				ResourceName:            "gitlab_user.foo",
+				ImportState:             true,
+				ImportStateId:           fmt.Sprintf("LISTEST%d", rInt),
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+			{
+				ResourceName:  "gitlab_user.foo",
+				ImportState:   true,
+				ImportStateId: fmt.Sprintf("missing%d", rInt),
+				ExpectError:   regexp.MustCompile("no user with this username"),
+			},
+		},
+	})
+}
+
 func testAccCheckGitlabUserExists(n string, user *gitlab.User) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -86,7 +129,7 @@ func testAccCheckGitlabUserExists(n string, user *gitlab.User) resource.TestChec
 		if userID == "" {
 			return fmt.Errorf("No user ID is set")
 		}
-		conn := testAccProvider.Meta().(*gitlab.Client)
+		conn := testAccProvider.Meta().(*providerMeta).client
 
 		id, _ := strconv.Atoi(userID)
 
@@ -125,7 +168,7 @@ func testAccCheckGitlabUserAttributes(user *gitlab.User, want *testAccGitlabUser
 }
 
 func testAccCheckGitlabUserDestroy(s *terraform.State) error {
-	conn := testAccProvider.Meta().(*gitlab.Client)
+	conn := testAccProvider.Meta().(*providerMeta).client
 
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "gitlab_user" {
@@ -134,13 +177,13 @@ func testAccCheckGitlabUserDestroy(s *terraform.State) error {
 
 		id, _ := strconv.Atoi(rs.Primary.ID)
 
-		user, resp, err := conn.Users.GetUser(id)
+		user, _, err := conn.Users.GetUser(id)
 		if err == nil {
 			if user != nil && fmt.Sprintf("%d", user.ID) == rs.Primary.ID {
 				return fmt.Errorf("User still exists")
 			}
 		}
-		if resp.StatusCode != 404 {
+		if !isNotFound(err) {
 			return err
 		}
 		return nil
@@ -0,0 +1,462 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryTransport is an http.RoundTripper that retries idempotent requests
+// which failed because GitLab was rate limiting us or was temporarily
+// unavailable.
+type retryTransport struct {
+	transport  http.RoundTripper
+	maxRetries int
+	waitMin    time.Duration
+	waitMax    time.Duration
+}
+
+// retryableStatusCodes are the response codes which are worth retrying.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// idempotentMethods are the only methods we can safely send more than once.
+var idempotentMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.maxRetries <= 0 || !idempotentMethods[req.Method] {
+		return t.transport.RoundTrip(req)
+	}
+
+	// Buffer the body so that it can be replayed on every attempt.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := new(http.Request)
+		*r = *req
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.transport.RoundTrip(r)
+		if err != nil || !retryableStatusCodes[resp.StatusCode] || attempt >= t.maxRetries {
+			return resp, err
+		}
+
+		wait := t.backoff(resp, attempt)
+		log.Printf("[DEBUG] %s %s returned %d, retrying in %s (%d/%d)",
+			req.Method, requestURL(req), resp.StatusCode, wait, attempt+1, t.maxRetries)
+
+		// Drain the body so the underlying connection can be reused.
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns how long to wait before the next attempt. The rate limit
+// headers sent by GitLab take precedence over the exponential backoff, but
+// are capped at waitMax too, so that a far off or skewed reset time does not
+// stall the request for hours.
+func (t *retryTransport) backoff(resp *http.Response, attempt int) time.Duration {
+	if wait, ok := retryAfter(resp, time.Now()); ok {
+		if wait > t.waitMax {
+			wait = t.waitMax
+		}
+		return wait
+	}
+
+	wait := t.waitMin
+	for i := 0; i < attempt && wait < t.waitMax; i++ {
+		wait *= 2
+	}
+	if wait > t.waitMax {
+		wait = t.waitMax
+	}
+	return wait
+}
+
+// retryAfter parses the Retry-After and RateLimit-Reset headers of resp. The
+// former is either a number of seconds or an HTTP date, the latter a unix
+// timestamp.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return nonNegative(at.Sub(now)), true
+		}
+	}
+
+	if v := resp.Header.Get("RateLimit-Reset"); v != "" {
+		if reset, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Unix(reset, 0).Sub(now)), true
+		}
+	}
+
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// throttleTransport caps the number of requests in flight and the rate at
+// which they are sent, so that a high -parallelism does not overwhelm the
+// GitLab instance.
+type throttleTransport struct {
+	transport http.RoundTripper
+
+	// slots holds a token per request in flight; nil when unlimited.
+	slots chan struct{}
+
+	// interval is the minimum time between two requests; 0 when unlimited.
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// newThrottleTransport wraps transport with the given limits. Zero values
+// disable the corresponding limit.
+func newThrottleTransport(transport http.RoundTripper, maxConcurrent int, requestsPerSecond float64) http.RoundTripper {
+	if maxConcurrent <= 0 && requestsPerSecond <= 0 {
+		return transport
+	}
+
+	t := &throttleTransport{transport: transport}
+	if maxConcurrent > 0 {
+		t.slots = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerSecond > 0 {
+		t.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return t
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if wait := t.reserve(time.Now()); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			t.release()
+			return nil, ctx.Err()
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		t.release()
+		return resp, err
+	}
+
+	// The request is only done once its body has been consumed.
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: t.release}
+	return resp, nil
+}
+
+// reserve books the next send slot and returns how long to wait for it.
+func (t *throttleTransport) reserve(now time.Time) time.Duration {
+	if t.interval == 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	return wait
+}
+
+func (t *throttleTransport) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// releaseOnClose calls release once, when the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// errorBodyTransport keeps the body of failed responses around. go-gitlab
+// consumes it to build its ErrorResponse, and apiError needs it afterwards to
+// turn GitLab's JSON error into a readable message.
+type errorBodyTransport struct {
+	transport http.RoundTripper
+}
+
+// errorBody is a response body which remembers its content.
+type errorBody struct {
+	*bytes.Reader
+	raw []byte
+}
+
+func (b *errorBody) Close() error {
+	return nil
+}
+
+func (t *errorBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, err
+	}
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &errorBody{Reader: bytes.NewReader(raw), raw: raw}
+	return resp, nil
+}
+
+// jobTokenTransport authenticates requests with a CI job token.
+type jobTokenTransport struct {
+	transport http.RoundTripper
+	token     string
+}
+
+func (t *jobTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+
+	r.Header.Del("PRIVATE-TOKEN")
+	r.Header.Set("JOB-TOKEN", t.token)
+	return t.transport.RoundTrip(r)
+}
+
+// traceTransport logs every request and response at TRACE level, with
+// credentials and other secrets redacted.
+type traceTransport struct {
+	transport http.RoundTripper
+}
+
+// redactedHeaders are never logged.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Job-Token":     true,
+	"Private-Token": true,
+	"Set-Cookie":    true,
+}
+
+// maxTracedBody caps how much of a body ends up in the logs.
+const maxTracedBody = 64 * 1024
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	log.Printf("[TRACE] GitLab API request: %s %s\n%s%s",
+		req.Method, redactURL(requestURL(req)), traceHeaders(req.Header), traceBody(req.Header, reqBody))
+
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		log.Printf("[TRACE] GitLab API request %s %s failed after %s: %s", req.Method, redactURL(requestURL(req)), elapsed, err)
+		return resp, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	log.Printf("[TRACE] GitLab API response: %s %s: %s in %s (X-Request-Id: %s)\n%s%s",
+		req.Method, redactURL(requestURL(req)), resp.Status, elapsed, resp.Header.Get("X-Request-Id"),
+		traceHeaders(resp.Header), traceBody(resp.Header, respBody))
+
+	return resp, nil
+}
+
+// requestURL returns the URL of req. go-gitlab sets URL.Opaque rather than
+// URL.Path, which URL.String does not render as one would expect.
+func requestURL(req *http.Request) string {
+	path := req.URL.Opaque
+	if path == "" {
+		path = req.URL.Path
+	}
+
+	u := fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, path)
+	if req.URL.RawQuery != "" {
+		u += "?" + req.URL.RawQuery
+	}
+	return u
+}
+
+func traceHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			v = "[REDACTED]"
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", k, v)
+	}
+	return buf.String()
+}
+
+func traceBody(h http.Header, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if !strings.Contains(h.Get("Content-Type"), "json") {
+		return fmt.Sprintf("\n[%d bytes of %q content]", len(body), h.Get("Content-Type"))
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Sprintf("\n[%d bytes of unparseable JSON]", len(body))
+	}
+
+	redacted, err := json.Marshal(redactJSON(v))
+	if err != nil {
+		return fmt.Sprintf("\n[%d bytes of JSON]", len(body))
+	}
+	if len(redacted) > maxTracedBody {
+		return fmt.Sprintf("\n%s... [truncated, %d bytes in total]", redacted[:maxTracedBody], len(redacted))
+	}
+	return "\n" + string(redacted)
+}
+
+// isSensitiveKey reports whether a JSON attribute or query parameter holds a
+// secret, such as a password, a hook or runners token, or a variable value.
+func isSensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	return k == "password" || k == "value" || k == "token" || k == "secret" ||
+		strings.HasSuffix(k, "_password") || strings.HasSuffix(k, "_token") || strings.HasSuffix(k, "_secret")
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			switch {
+			case isSensitiveKey(k):
+				if value != nil && value != "" {
+					v[k] = "[REDACTED]"
+				}
+			case strings.HasSuffix(strings.ToLower(k), "url"):
+				if s, ok := value.(string); ok {
+					v[k] = redactURL(s)
+				}
+			default:
+				v[k] = redactJSON(value)
+			}
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactURL removes the credentials embedded in the user info or in the
+// query of rawurl.
+func redactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if isSensitiveKey(k) {
+				q.Set(k, "REDACTED")
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
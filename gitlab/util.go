@@ -1,8 +1,17 @@
 package gitlab
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	gitlab "github.com/xanzy/go-gitlab"
 )
@@ -39,3 +48,297 @@ func stringToVisibilityLevel(s string) *gitlab.VisibilityValue {
 	}
 	return &value
 }
+
+// sudoOptions returns the request options needed to make an API call on
+// behalf of the user given in the resource's sudo argument, falling back to
+// the provider's default.
+func sudoOptions(d *schema.ResourceData, meta interface{}) []gitlab.OptionFunc {
+	sudo := meta.(*providerMeta).sudo
+	if v, ok := d.GetOk("sudo"); ok {
+		sudo = v.(string)
+	}
+
+	if sudo == "" {
+		return nil
+	}
+	return []gitlab.OptionFunc{gitlab.WithSudo(sudo)}
+}
+
+// paginate calls fetch for every page of a list, following the X-Next-Page
+// header sent by GitLab, and returns the last response. fetch is handed the
+// options to request the page with.
+func paginate(fetch func(opt *gitlab.ListOptions) (*gitlab.Response, error)) (*gitlab.Response, error) {
+	opt := &gitlab.ListOptions{Page: 1, PerPage: 100}
+	for {
+		resp, err := fetch(opt)
+		if err != nil {
+			return resp, err
+		}
+
+		next := nextPage(resp)
+		if next <= opt.Page {
+			return resp, nil
+		}
+		opt.Page = next
+	}
+}
+
+// nextPage returns the page following resp, or 0 on the last page.
+func nextPage(resp *gitlab.Response) int {
+	if v := resp.Header.Get("X-Next-Page"); v != "" {
+		if page, err := strconv.Atoi(v); err == nil {
+			return page
+		}
+	}
+	return resp.NextPage
+}
+
+// listLabels returns every label of project. go-gitlab's ListLabels only
+// returns the first page.
+func listLabels(client *gitlab.Client, project string, options ...gitlab.OptionFunc) ([]*gitlab.Label, *gitlab.Response, error) {
+	var labels []*gitlab.Label
+	resp, err := paginate(func(opt *gitlab.ListOptions) (*gitlab.Response, error) {
+		req, err := client.NewRequest("GET", fmt.Sprintf("projects/%s/labels", url.QueryEscape(project)), opt, options)
+		if err != nil {
+			return nil, err
+		}
+
+		var page []*gitlab.Label
+		resp, err := client.Do(req, &page)
+		labels = append(labels, page...)
+		return resp, err
+	})
+	return labels, resp, err
+}
+
+// apiStatusCode returns the HTTP status of the GitLab API error err, or 0 when
+// the request failed before a response was received (DNS, TLS, timeouts...).
+func apiStatusCode(err error) int {
+	if e, ok := err.(*gitlab.ErrorResponse); ok && e.Response != nil {
+		return e.Response.StatusCode
+	}
+	return 0
+}
+
+func isNotFound(err error) bool {
+	return apiStatusCode(err) == http.StatusNotFound
+}
+
+func isConflict(err error) bool {
+	return apiStatusCode(err) == http.StatusConflict
+}
+
+func isForbidden(err error) bool {
+	return apiStatusCode(err) == http.StatusForbidden
+}
+
+// apiError prefixes err with what we were doing, and rewrites GitLab API
+// errors using the message GitLab sent back, so that users get e.g. `Error
+// creating project "foo": POST https://gitlab.com/api/v4/projects: 400 Bad
+// Request: name has already been taken`.
+func apiError(err error, format string, a ...interface{}) error {
+	what := fmt.Sprintf(format, a...)
+
+	e, ok := err.(*gitlab.ErrorResponse)
+	if !ok || e.Response == nil || e.Response.Request == nil {
+		return fmt.Errorf("Error %s: %s", what, err)
+	}
+
+	message := e.Message
+	if body, ok := e.Response.Body.(*errorBody); ok {
+		if m := parseAPIErrorMessage(body.raw); m != "" {
+			message = m
+		}
+	}
+
+	if isForbidden(err) {
+		message += " (check that the token has the permissions required for this operation)"
+	}
+
+	return fmt.Errorf("Error %s: %s %s: %s: %s", what, e.Response.Request.Method,
+		requestURL(e.Response.Request), e.Response.Status, message)
+}
+
+// parseAPIErrorMessage formats the JSON error document GitLab sends along with
+// failed responses. It is either {"message": ...}, where the message is a
+// string or a map of attribute names to their errors, or an OAuth style
+// {"error": ..., "error_description": ...}.
+func parseAPIErrorMessage(raw []byte) string {
+	var doc struct {
+		Message          interface{} `json:"message"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+
+	if doc.Message != nil {
+		return formatAPIErrorMessage("", doc.Message)
+	}
+
+	if doc.ErrorDescription != "" {
+		return fmt.Sprintf("%s: %s", doc.Error, doc.ErrorDescription)
+	}
+	return doc.Error
+}
+
+func formatAPIErrorMessage(attr string, v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if attr == "" {
+			return v
+		}
+		return attr + " " + v
+	case []interface{}:
+		var msgs []string
+		for _, item := range v {
+			msgs = append(msgs, formatAPIErrorMessage(attr, item))
+		}
+		return strings.Join(msgs, "; ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var msgs []string
+		for _, k := range keys {
+			name := k
+			if attr != "" {
+				name = attr + "." + k
+			}
+			msgs = append(msgs, formatAPIErrorMessage(name, v[k]))
+		}
+		return strings.Join(msgs, "; ")
+	default:
+		return fmt.Sprintf("%s %v", attr, v)
+	}
+}
+
+// waitForDeletion waits, for at most timeout, until an object GitLab deletes
+// asynchronously is gone. read returns whether the object is marked for
+// deletion, and the error from fetching it. With delayed deletion, GitLab
+// keeps groups and projects around for days once marked, so being marked
+// counts as deleted.
+func waitForDeletion(what string, timeout time.Duration, read func() (bool, error)) error {
+	stateConf := &resource.StateChangeConf{
+		Pending: []string{"Deleting"},
+		Target:  []string{"Deleted"},
+		Refresh: func() (interface{}, string, error) {
+			marked, err := read()
+			if isNotFound(err) {
+				return what, "Deleted", nil
+			}
+			if err != nil {
+				log.Printf("[ERROR] Received error: %#v", err)
+				return what, "Error", apiError(err, "reading %s", what)
+			}
+			if marked {
+				log.Printf("[DEBUG] %s is marked for deletion", what)
+				return what, "Deleted", nil
+			}
+			return what, "Deleting", nil
+		},
+
+		Timeout:    timeout,
+		MinTimeout: 3 * time.Second,
+	}
+
+	if _, err := stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("error waiting for %s to become deleted: %s", what, err)
+	}
+	return nil
+}
+
+// checkDeletionProtection returns an error when d has deletion_protection
+// set. Delete calls it first, which also stops plans replacing the resource
+// from being applied.
+func checkDeletionProtection(d *schema.ResourceData, what string) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("%s is protected against deletion: set deletion_protection to false and apply before destroying or replacing it", what)
+	}
+	return nil
+}
+
+// parseProjectImportID splits the ID given to terraform import for a resource
+// which belongs to a project, such as "group/project:name", on its first
+// colon: project paths never contain one, but label names may.
+func parseProjectImportID(id, child string) (string, string, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("unexpected format of ID (%s), expected project:%s", id, child)
+	}
+	return parts[0], parts[1], nil
+}
+
+// projectSchema returns the schema of the project argument of resources which
+// belong to a project, such as labels and hooks. It takes the ID or full path
+// of the project, and keeps the configured value: Read records the numeric ID
+// in project_id, which API calls use, the current full path in project_path,
+// and every full path the project had in project_paths, so that renaming or
+// moving the project does not change the resource.
+func projectSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeString,
+		Required:         true,
+		ForceNew:         true,
+		DiffSuppressFunc: suppressSameProject,
+	}
+}
+
+// suppressSameProject suppresses diffs between the ID and the full paths of the
+// same project, current or former, whichever form the state records, and
+// between full paths which only differ by case, which GitLab ignores.
+func suppressSameProject(k, old, new string, d *schema.ResourceData) bool {
+	if strings.EqualFold(old, new) {
+		return true
+	}
+	if id := d.Get("project_id").(int); id != 0 && new == strconv.Itoa(id) {
+		return true
+	}
+	for _, path := range d.Get("project_paths").([]interface{}) {
+		if strings.EqualFold(new, path.(string)) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectOf returns the project a resource belongs to as API calls take it:
+// its numeric ID once it is known, or else the project argument.
+func projectOf(d *schema.ResourceData) string {
+	if id := d.Get("project_id").(int); id != 0 {
+		return strconv.Itoa(id)
+	}
+	return d.Get("project").(string)
+}
+
+// readProjectOf looks up the project a resource belongs to and records its
+// numeric ID and current full path in project_id and project_path. The path is
+// also added to project_paths, so that the configuration can keep referring to
+// the project by a former path, whichever form the state records.
+func readProjectOf(d *schema.ResourceData, client *gitlab.Client, options ...gitlab.OptionFunc) error {
+	project, _, err := client.Projects.GetProject(projectOf(d), options...)
+	if err != nil {
+		return err
+	}
+
+	paths := d.Get("project_paths").([]interface{})
+	known := false
+	for _, path := range paths {
+		if strings.EqualFold(path.(string), project.PathWithNamespace) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		paths = append(paths, project.PathWithNamespace)
+	}
+
+	d.Set("project_id", project.ID)
+	d.Set("project_path", project.PathWithNamespace)
+	d.Set("project_paths", paths)
+	return nil
+}
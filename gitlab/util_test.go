@@ -1,7 +1,12 @@
 package gitlab
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/xanzy/go-gitlab"
 )
@@ -63,3 +68,221 @@ func TestGitlab_visbilityHelpers(t *testing.T) {
 		}
 	}
 }
+
+func TestGitlab_sudoOptions(t *testing.T) {
+	cases := []struct {
+		Provider string
+		Resource string
+		Header   string
+	}{
+		{"", "", ""},
+		{"jdoe", "", "jdoe"},
+		{"", "42", "42"},
+		{"jdoe", "alice", "alice"},
+	}
+
+	for _, tc := range cases {
+		d := resourceGitlabProject().TestResourceData()
+		d.Set("sudo", tc.Resource)
+		meta := &providerMeta{sudo: tc.Provider}
+
+		req, _ := http.NewRequest("GET", "https://gitlab.com/api/v4/projects", nil)
+		for _, fn := range sudoOptions(d, meta) {
+			if err := fn(req); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+
+		if v := req.Header.Get("SUDO"); v != tc.Header {
+			t.Fatalf("got SUDO %q; want %q", v, tc.Header)
+		}
+	}
+}
+
+func TestGitlab_listLabels(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/foo%2Fbar/labels" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		switch page {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"name": "bug"}, {"name": "feature"}]`)
+		case "2":
+			w.Header().Set("X-Next-Page", "3")
+			fmt.Fprint(w, `[{"name": "docs"}]`)
+		default:
+			w.Header().Set("X-Next-Page", "")
+			fmt.Fprint(w, `[{"name": "FIXME"}]`)
+		}
+	}))
+	defer server.Close()
+
+	client := gitlab.NewClient(nil, "secret")
+	client.SetBaseURL(server.URL + "/api/v4/")
+
+	labels, _, err := listLabels(client, "foo/bar")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	if got := strings.Join(names, ","); got != "bug,feature,docs,FIXME" {
+		t.Fatalf("got labels %s; want bug,feature,docs,FIXME", got)
+	}
+	if got := strings.Join(pages, ","); got != "1,2,3" {
+		t.Fatalf("got pages %s; want 1,2,3", got)
+	}
+
+	_, resp, err := listLabels(client, "foo/missing")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("got (%v, %v); want a 404 error", resp, err)
+	}
+}
+
+func TestGitlab_apiError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/projects/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "404 Project Not Found"}`)
+		case "/api/v4/projects":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message": {"name": ["has already been taken"], "path": ["has already been taken", "is too short"]}}`)
+		case "/api/v4/user":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_token", "error_description": "Token was revoked."}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "403 Forbidden"}`)
+		}
+	}))
+	defer server.Close()
+
+	c := &Config{Token: "secret", BaseURL: server.URL + "/api/v4/", SkipCredentialsValidation: true}
+	raw, err := c.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := raw.(*providerMeta).client
+
+	_, _, err = client.Projects.GetProject("missing")
+	if !isNotFound(err) || isConflict(err) || isForbidden(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	want := fmt.Sprintf(`Error reading project missing: GET %s/api/v4/projects/missing: 404 Not Found: 404 Project Not Found`, server.URL)
+	if got := apiError(err, "reading project %s", "missing").Error(); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	_, _, err = client.Projects.CreateProject(&gitlab.CreateProjectOptions{Name: gitlab.String("foo")})
+	want = "400 Bad Request: name has already been taken; path has already been taken; path is too short"
+	if got := apiError(err, "creating project %q", "foo").Error(); !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q; want it to end with %q", got, want)
+	}
+
+	_, _, err = client.Users.CurrentUser()
+	want = "401 Unauthorized: invalid_token: Token was revoked."
+	if got := apiError(err, "reading the current user").Error(); !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q; want it to end with %q", got, want)
+	}
+
+	_, _, err = client.Groups.GetGroup("secret")
+	if !isForbidden(err) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if got := apiError(err, "reading group secret").Error(); !strings.Contains(got, "check that the token has the permissions") {
+		t.Fatalf("got %q; want a hint about permissions", got)
+	}
+
+	// Requests failing before a response arrives must not panic.
+	err = fmt.Errorf("dial tcp: lookup gitlab.invalid: no such host")
+	if isNotFound(err) {
+		t.Fatalf("a network error is not a not found error")
+	}
+	if got := apiError(err, "reading project 1").Error(); got != "Error reading project 1: dial tcp: lookup gitlab.invalid: no such host" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestGitlab_waitForDeletion(t *testing.T) {
+	notFound := &gitlab.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+
+	if err := waitForDeletion("project 1", time.Minute, func() (bool, error) { return false, notFound }); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Delayed deletion: the project is only marked for deletion.
+	if err := waitForDeletion("project 1", time.Minute, func() (bool, error) { return true, nil }); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := waitForDeletion("project 1", time.Millisecond, func() (bool, error) { return false, nil })
+	if err == nil || !strings.Contains(err.Error(), "error waiting for project 1 to become deleted") {
+		t.Fatalf("got error %v; want a timeout", err)
+	}
+}
+
+func TestGitlab_parseProjectImportID(t *testing.T) {
+	cases := []struct {
+		ID      string
+		Project string
+		Child   string
+		Err     bool
+	}{
+		{"42:7", "42", "7", false},
+		{"group/subgroup/project:bug", "group/subgroup/project", "bug", false},
+		{"group/project:scope::label", "group/project", "scope::label", false},
+		{"group/project", "", "", true},
+		{":bug", "", "", true},
+		{"group/project:", "", "", true},
+	}
+
+	for _, tc := range cases {
+		project, child, err := parseProjectImportID(tc.ID, "label_name")
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: got error %v; want error: %t", tc.ID, err, tc.Err)
+		}
+		if project != tc.Project || child != tc.Child {
+			t.Fatalf("%s: got (%q, %q); want (%q, %q)", tc.ID, project, child, tc.Project, tc.Child)
+		}
+	}
+}
+
+func TestGitlab_suppressSameProject(t *testing.T) {
+	cases := []struct {
+		Old      string
+		New      string
+		Suppress bool
+	}{
+		{"group/project", "group/project", true},
+		{"group/project", "Group/Project", true},
+		{"group/project", "42", true},
+		{"group/project", "43", false},
+		{"group/project", "group/other", false},
+		{"group/project", "group/renamed", true},
+		{"42", "Group/Renamed", true},
+		{"42", "group/project", true},
+		{"42", "group/other", false},
+	}
+
+	for _, tc := range cases {
+		d := resourceGitlabLabel().TestResourceData()
+		d.Set("project_id", 42)
+		d.Set("project_path", "group/renamed")
+		d.Set("project_paths", []string{"group/project", "group/renamed"})
+
+		if v := suppressSameProject("project", tc.Old, tc.New, d); v != tc.Suppress {
+			t.Fatalf("%s => %s: got %t; want %t", tc.Old, tc.New, v, tc.Suppress)
+		}
+	}
+}